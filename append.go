@@ -0,0 +1,323 @@
+package ar
+
+import (
+	"errors"
+	"fmt"
+	"io"
+)
+
+// AppenderOptions specifies optional behaviour for an Appender created with NewAppender.
+type AppenderOptions struct {
+	// WriterOptions specifies how the archive is written. If the existing archive is a thin archive,
+	// Thin is set automatically; otherwise, Thin only takes effect if the archive is empty. If the
+	// existing archive has a symbol table, SymbolIndex is set automatically, so that the symbol table
+	// is regenerated to include the symbols defined by the appended members.
+	WriterOptions
+
+	// Variant is the variant of the ar file format used if the archive is empty or has no members, in
+	// which case its variant can't be detected.
+	Variant Variant
+
+	// Replace causes an appended member to replace the first existing member with the same name (like
+	// GNU ar's "r" command), rather than being added after the existing members (like GNU ar's "q"
+	// command). Appended members that don't replace an existing member are added after the existing
+	// members.
+	Replace bool
+
+	// Resolver opens the external files containing the data sections of the existing members of a thin
+	// archive. It is only needed if the archive's symbol table must be regenerated.
+	Resolver Resolver
+
+	// Rewrite, if set, is called when the entire archive must be rewritten, instead of overwriting the
+	// archive file in place. It is passed a function that writes the new archive to an io.Writer, which
+	// reads the existing members from the archive file as it goes. This allows the caller to write the
+	// new archive to a temporary file and then replace the archive file with it, so that the archive
+	// file is left intact if an error occurs part of the way through.
+	Rewrite func(write func(io.Writer) error) error
+}
+
+// Appender adds members to an existing ar archive. Members are supplied in the same way as to a
+// Writer, by calling WriteHeader followed by Write; they are held by the Appender until Close is
+// called, at which point the archive is updated.
+//
+// If possible, the new members are simply written after the end of the existing archive, leaving
+// the existing members untouched. If the archive has a symbol table (or the SymbolIndex option is
+// set), if any members are replaced, or if an appended member of a GNU-variant archive has a long
+// file name that must be added to the string table, the entire archive is rewritten instead (either
+// in place, or by the Rewrite option, if it is set).
+//
+// Example:
+//
+//	f, err := os.OpenFile("lib.a", os.O_RDWR, 0)
+//	if err != nil {
+//		return err
+//	}
+//	defer f.Close()
+//	appender, err := ar.NewAppender(f, ar.AppenderOptions{})
+//	if err != nil {
+//		return err
+//	}
+//	if err := appender.WriteHeader(hdr); err != nil {
+//		return err
+//	}
+//	io.Copy(appender, data)
+//	return appender.Close()
+type Appender struct {
+	// f is the archive file.
+	f io.ReadWriteSeeker
+
+	// size is the size of the existing archive file in bytes.
+	size int64
+
+	// archive provides access to the existing archive's members, or is nil if the archive file is
+	// empty.
+	archive *Archive
+
+	// variant is the variant of the ar file format used by the archive.
+	variant Variant
+
+	opts AppenderOptions
+
+	// w is a buffered Writer that holds the appended members until Close is called.
+	w *Writer
+}
+
+// NewAppender returns an Appender that adds members to the archive in f. f is read immediately to
+// determine the archive's variant and members; it is only written to when Close is called. If f is
+// empty, a new archive is created.
+func NewAppender(f io.ReadWriteSeeker, opts AppenderOptions) (*Appender, error) {
+	size, err := f.Seek(0, io.SeekEnd)
+	if err != nil {
+		return nil, fmt.Errorf("ar: %w", err)
+	}
+	a := &Appender{
+		f:       f,
+		size:    size,
+		variant: opts.Variant,
+		opts:    opts,
+	}
+	if size > 0 {
+		a.archive, err = NewArchive(readerAt(f), size)
+		if err != nil {
+			return nil, err
+		}
+		if len(a.archive.Files) > 0 || a.archive.Symbols() != nil {
+			a.variant = a.archive.Variant()
+		}
+		// Thin archives always use the GNU variant, even if they're empty.
+		if a.archive.Thin() {
+			a.variant = GNU
+		}
+		a.opts.Thin = a.archive.Thin()
+		if a.archive.Symbols() != nil {
+			a.opts.SymbolIndex = true
+		}
+	}
+	if a.opts.Thin && a.variant != GNU {
+		return nil, errors.New("ar: thin archives must use the GNU variant")
+	}
+	bufferOpts := a.opts.WriterOptions
+	bufferOpts.Buffer = true
+	a.w = NewWriterWithOptions(nil, a.variant, bufferOpts)
+	return a, nil
+}
+
+// Variant returns the ar file format variant used by the archive.
+func (a *Appender) Variant() Variant {
+	return a.variant
+}
+
+// WriteHeader begins a new archive member with the given header. Data for the member's data section
+// can then be supplied by calling Write.
+func (a *Appender) WriteHeader(hdr *Header) error {
+	return a.w.WriteHeader(hdr)
+}
+
+// Write supplies data for the data section of the current archive member. It returns
+// ErrWriteTooLong if more than hdr.Size bytes are written after a call to WriteHeader.
+func (a *Appender) Write(b []byte) (int, error) {
+	return a.w.Write(b)
+}
+
+// Close updates the archive file to include the appended members. It does not close the underlying
+// archive file.
+func (a *Appender) Close() error {
+	if a.w.closed {
+		return errors.New("ar: appender closed twice")
+	}
+	if a.w.nb > 0 && !a.w.discardsData() {
+		return fmt.Errorf("ar: missed writing %d bytes", a.w.nb)
+	}
+	a.w.closed = true
+	err := a.update()
+	if cerr := a.w.spool.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// update writes the appended members to the archive file.
+func (a *Appender) update() error {
+	existing, err := a.existingMembers()
+	if err != nil {
+		return err
+	}
+	data := a.w.spool.ReaderAt()
+	appended := make([]member, len(a.w.members))
+	for i, m := range a.w.members {
+		appended[i] = member{
+			hdr:  m.hdr,
+			data: io.NewSectionReader(data, m.off, m.hdr.Size),
+		}
+		if a.w.discardsData() {
+			appended[i].data = io.NewSectionReader(zeroReaderAt{}, 0, m.hdr.Size)
+		}
+	}
+	members, replaced := existing, false
+	for _, m := range appended {
+		if i := a.replacement(members[:len(existing)], m.hdr.Name); i >= 0 {
+			members[i] = m
+			replaced = true
+		} else {
+			members = append(members, m)
+		}
+	}
+	if !replaced && !a.opts.SymbolIndex && !a.needsStringTable(appended) {
+		return a.append(appended)
+	}
+	return a.rewrite(members)
+}
+
+// existingMembers returns the members of the existing archive.
+func (a *Appender) existingMembers() ([]member, error) {
+	if a.archive == nil {
+		return nil, nil
+	}
+	return archiveMembers(a.archive, a.opts.SymbolIndex, a.opts.Resolver, a.w.spool)
+}
+
+// replacement returns the index of the existing member that should be replaced by an appended member
+// with the given name, or -1 if the appended member should be added after the existing members.
+func (a *Appender) replacement(existing []member, name string) int {
+	if !a.opts.Replace {
+		return -1
+	}
+	for i, m := range existing {
+		if m.hdr.Name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// needsStringTable returns whether any of the given members have names that must be stored in a GNU
+// string table.
+func (a *Appender) needsStringTable(members []member) bool {
+	if a.variant != GNU {
+		return false
+	}
+	for _, m := range members {
+		if a.w.longName(m.hdr.Name) {
+			return true
+		}
+	}
+	return false
+}
+
+// append writes the given members after the end of the existing archive.
+func (a *Appender) append(members []member) error {
+	if _, err := a.f.Seek(a.size, io.SeekStart); err != nil {
+		return fmt.Errorf("ar: %w", err)
+	}
+	// Members' data sections are padded to an even number of bytes, but not all archivers pad the
+	// final member.
+	if a.size%2 == 1 {
+		if _, err := a.f.Write([]byte{'\n'}); err != nil {
+			return fmt.Errorf("ar: write archive member padding: %w", err)
+		}
+	}
+	out := a.newWriter(a.f)
+	out.wroteHeader = a.size > 0
+	if err := out.writeArchive(members, nil); err != nil {
+		return err
+	}
+	return out.Close()
+}
+
+// newWriter returns a Writer that writes archive members to w in the same format as the existing
+// archive.
+func (a *Appender) newWriter(w io.Writer) *Writer {
+	out := newWriter(w, a.variant, a.opts.WriterOptions)
+	out.coff = a.archive != nil && a.archive.COFFLinkerMember() != nil
+	return out
+}
+
+// truncater is implemented by files that can be truncated, such as *os.File.
+type truncater interface {
+	Truncate(size int64) error
+}
+
+// rewrite replaces the contents of the archive file with an archive containing the given members,
+// or passes the new archive to the Rewrite option if it is set.
+func (a *Appender) rewrite(members []member) error {
+	if a.opts.Rewrite != nil {
+		return a.opts.Rewrite(func(w io.Writer) error {
+			out := a.newWriter(w)
+			if err := out.writeArchive(members, nil); err != nil {
+				return err
+			}
+			return out.Close()
+		})
+	}
+	buf := &spool{max: a.w.spool.max}
+	defer buf.Close()
+	out := a.newWriter(buf)
+	if err := out.writeArchive(members, nil); err != nil {
+		return err
+	}
+	if err := out.Close(); err != nil {
+		return err
+	}
+	t, canTruncate := a.f.(truncater)
+	if buf.Len() < a.size && !canTruncate {
+		return errors.New("ar: archive file can't be truncated")
+	}
+	if _, err := a.f.Seek(0, io.SeekStart); err != nil {
+		return fmt.Errorf("ar: %w", err)
+	}
+	if _, err := io.Copy(a.f, io.NewSectionReader(buf.ReaderAt(), 0, buf.Len())); err != nil {
+		return fmt.Errorf("ar: rewrite archive: %w", err)
+	}
+	if buf.Len() < a.size {
+		if err := t.Truncate(buf.Len()); err != nil {
+			return fmt.Errorf("ar: rewrite archive: %w", err)
+		}
+	}
+	return nil
+}
+
+// readerAt returns an io.ReaderAt that reads from r, which must also implement io.Seeker if it
+// doesn't implement io.ReaderAt itself.
+func readerAt(r io.ReadSeeker) io.ReaderAt {
+	if ra, ok := r.(io.ReaderAt); ok {
+		return ra
+	}
+	return seekReaderAt{r}
+}
+
+// seekReaderAt implements io.ReaderAt by seeking within an io.ReadSeeker. It is not safe for
+// concurrent use.
+type seekReaderAt struct {
+	r io.ReadSeeker
+}
+
+func (s seekReaderAt) ReadAt(b []byte, off int64) (int, error) {
+	if _, err := s.r.Seek(off, io.SeekStart); err != nil {
+		return 0, err
+	}
+	n, err := io.ReadFull(s.r, b)
+	if err == io.ErrUnexpectedEOF {
+		err = io.EOF
+	}
+	return n, err
+}
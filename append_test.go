@@ -0,0 +1,313 @@
+package ar
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memFile is an in-memory io.ReadWriteSeeker that implements neither io.ReaderAt nor Truncate.
+type memFile struct {
+	data []byte
+	pos  int64
+}
+
+func (f *memFile) Read(b []byte) (int, error) {
+	if f.pos >= int64(len(f.data)) {
+		return 0, io.EOF
+	}
+	n := copy(b, f.data[f.pos:])
+	f.pos += int64(n)
+	return n, nil
+}
+
+func (f *memFile) Write(b []byte) (int, error) {
+	if end := f.pos + int64(len(b)); end > int64(len(f.data)) {
+		f.data = append(f.data, make([]byte, end-int64(len(f.data)))...)
+	}
+	n := copy(f.data[f.pos:], b)
+	f.pos += int64(n)
+	return n, nil
+}
+
+func (f *memFile) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekCurrent:
+		offset += f.pos
+	case io.SeekEnd:
+		offset += int64(len(f.data))
+	}
+	f.pos = offset
+	return offset, nil
+}
+
+// copyFile copies a file from the test_data directory to a temporary directory, and opens the copy
+// for reading and writing.
+func copyFile(t *testing.T, name string) *os.File {
+	data, err := os.ReadFile("./test_data/" + name)
+	require.NoError(t, err)
+	path := filepath.Join(t.TempDir(), filepath.Base(name))
+	require.NoError(t, os.WriteFile(path, data, 0644))
+	f, err := os.OpenFile(path, os.O_RDWR, 0)
+	require.NoError(t, err)
+	t.Cleanup(func() { f.Close() })
+	return f
+}
+
+func appendMember(t *testing.T, appender *Appender, name, data string) {
+	require.NoError(t, appender.WriteHeader(&Header{Name: name, ModTime: time.Unix(0, 0), Mode: 0644, Size: int64(len(data))}))
+	_, err := appender.Write([]byte(data))
+	require.NoError(t, err)
+}
+
+func TestAppend(t *testing.T) {
+	for _, tc := range []struct {
+		Description string
+		Variant     Variant
+		Name        string
+		// Rewrite is true if the archive must be rewritten rather than appended to.
+		Rewrite bool
+	}{
+		{"GNU format", GNU, "b.txt", false},
+		{"GNU format with long file name", GNU, "a_very_long_file_name.txt", true},
+		{"BSD format", BSD, "b.txt", false},
+		{"BSD format with long file name", BSD, "a_very_long_file_name.txt", false},
+	} {
+		t.Run(tc.Description, func(t *testing.T) {
+			var buf bytes.Buffer
+			writer := NewWriter(&buf, tc.Variant)
+			require.NoError(t, writer.WriteHeader(&Header{Name: "a.txt", Mode: 0644, Size: 3}))
+			_, err := writer.Write([]byte("odd"))
+			require.NoError(t, err)
+			require.NoError(t, writer.Close())
+			original := append([]byte(nil), buf.Bytes()...)
+
+			f := &memFile{data: buf.Bytes()}
+			appender, err := NewAppender(f, AppenderOptions{Variant: 1 - tc.Variant})
+			require.NoError(t, err)
+			assert.Equal(t, tc.Variant, appender.Variant())
+			appendMember(t, appender, tc.Name, "even")
+			require.NoError(t, appender.Close())
+			assert.Equal(t, !tc.Rewrite, bytes.HasPrefix(f.data, original))
+
+			reader, err := NewReader(bytes.NewReader(f.data))
+			require.NoError(t, err)
+			assert.Equal(t, tc.Variant, reader.Variant())
+			for _, expected := range []struct{ Name, Data string }{{"a.txt", "odd"}, {tc.Name, "even"}} {
+				hdr, err := reader.Next()
+				require.NoError(t, err)
+				assert.Equal(t, expected.Name, hdr.Name)
+				data, err := io.ReadAll(reader)
+				require.NoError(t, err)
+				assert.Equal(t, expected.Data, string(data))
+			}
+			_, err = reader.Next()
+			assert.ErrorIs(t, err, io.EOF)
+		})
+	}
+}
+
+func TestAppendToEmptyFile(t *testing.T) {
+	f := &memFile{}
+	appender, err := NewAppender(f, AppenderOptions{Variant: GNU})
+	require.NoError(t, err)
+	appendMember(t, appender, "a_very_long_file_name.txt", "data")
+	require.NoError(t, appender.Close())
+
+	reader, err := NewReader(bytes.NewReader(f.data))
+	require.NoError(t, err)
+	hdr, err := reader.Next()
+	require.NoError(t, err)
+	assert.Equal(t, GNU, reader.Variant())
+	assert.Equal(t, "a_very_long_file_name.txt", hdr.Name)
+}
+
+func TestAppendRegeneratesSymbolTable(t *testing.T) {
+	f := copyFile(t, "symbols_gnu.a")
+	appender, err := NewAppender(f, AppenderOptions{})
+	require.NoError(t, err)
+	obj, err := os.ReadFile("./test_data/objs/sub/a_long_object_name.o")
+	require.NoError(t, err)
+	appendMember(t, appender, "a_long_object_name.o", string(obj))
+	txt, err := os.ReadFile("./test_data/objs/h.txt")
+	require.NoError(t, err)
+	appendMember(t, appender, "h.txt", string(txt))
+	require.NoError(t, appender.Close())
+
+	// GNU ar leaves the string table's numeric header fields blank, so the archives aren't
+	// byte-for-byte identical, but their members and symbol tables should be.
+	expectedHdrs, expectedData := readMembers(t, "./test_data/symbols_gnu_appended.a")
+	actualHdrs, actualData := readMembers(t, f.Name())
+	assert.Equal(t, expectedHdrs, actualHdrs)
+	assert.Equal(t, expectedData, actualData)
+	fi, err := f.Stat()
+	require.NoError(t, err)
+	archive, err := NewArchive(f, fi.Size())
+	require.NoError(t, err)
+	assert.Equal(t, openArchive(t, "./test_data/symbols_gnu_appended.a").Symbols(), archive.Symbols())
+}
+
+func TestAppendCOFF(t *testing.T) {
+	f := copyFile(t, "coff_import.lib")
+	appender, err := NewAppender(f, AppenderOptions{})
+	require.NoError(t, err)
+	obj, err := os.ReadFile("./test_data/coff.obj")
+	require.NoError(t, err)
+	appendMember(t, appender, "a_long_object_name.obj", string(obj))
+	require.NoError(t, appender.Close())
+
+	fi, err := f.Stat()
+	require.NoError(t, err)
+	archive, err := NewArchive(f, fi.Size())
+	require.NoError(t, err)
+	require.Len(t, archive.Files, 8)
+	assert.Equal(t, "a_long_object_name.obj", archive.Files[7].Name)
+	symbols := archive.Symbols()
+	require.Len(t, symbols, 15)
+	for name, f := range openArchive(t, "./test_data/coff_import.lib").SymbolTable() {
+		assert.Equal(t, f.Name, archive.SymbolTable()[name].Name)
+	}
+	assert.Equal(t, Symbol{Name: "counter", Offset: archive.Files[7].HeaderOffset()}, symbols[13])
+	// The second linker member should list the same symbols, sorted by name.
+	lm := archive.COFFLinkerMember()
+	require.NotNil(t, lm)
+	require.Len(t, lm.Offsets, 8)
+	assert.Equal(t, archive.Files[7].HeaderOffset(), lm.Offsets[7])
+	sorted := append([]Symbol{}, symbols...)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Name < sorted[j].Name })
+	assert.Equal(t, sorted, lm.Symbols)
+}
+
+func TestAppendReplace(t *testing.T) {
+	f := copyFile(t, "long_filenames_gnu.a")
+	appender, err := NewAppender(f, AppenderOptions{Replace: true})
+	require.NoError(t, err)
+	appendMember(t, appender, "20xxxxxxxxxxxxxxxxxx", "replaced")
+	appendMember(t, appender, "3xx", "replaced")
+	appendMember(t, appender, "21x", "appended")
+	require.NoError(t, appender.Close())
+
+	hdrs, data := readMembers(t, f.Name())
+	require.Len(t, hdrs, 21)
+	assert.Equal(t, "3xx", hdrs[2].Name)
+	assert.Equal(t, "replaced", string(data[2]))
+	assert.Equal(t, "20xxxxxxxxxxxxxxxxxx", hdrs[19].Name)
+	assert.Equal(t, "replaced", string(data[19]))
+	assert.Equal(t, "21x", hdrs[20].Name)
+	assert.Equal(t, "appended", string(data[20]))
+	// The archive must have been truncated after the last member.
+	fi, err := f.Stat()
+	require.NoError(t, err)
+	archive, err := NewArchive(f, fi.Size())
+	require.NoError(t, err)
+	last := archive.Files[len(archive.Files)-1]
+	assert.Equal(t, last.DataOffset()+last.Size, fi.Size())
+	original, err := os.Stat("./test_data/long_filenames_gnu.a")
+	require.NoError(t, err)
+	assert.Less(t, fi.Size(), original.Size())
+}
+
+func TestAppendReplaceWithoutTruncate(t *testing.T) {
+	data, err := os.ReadFile("./test_data/long_filenames_gnu.a")
+	require.NoError(t, err)
+	f := &memFile{data: data}
+	appender, err := NewAppender(f, AppenderOptions{Replace: true})
+	require.NoError(t, err)
+	appendMember(t, appender, "1", "")
+	assert.Error(t, appender.Close())
+	assert.Equal(t, data, f.data)
+}
+
+func TestAppendRewriteOption(t *testing.T) {
+	data, err := os.ReadFile("./test_data/long_filenames_gnu.a")
+	require.NoError(t, err)
+	var rewritten bytes.Buffer
+	rewrite := func(write func(io.Writer) error) error {
+		return write(&rewritten)
+	}
+
+	// Members that can be appended are written to the archive file...
+	f := &memFile{data: append([]byte(nil), data...)}
+	appender, err := NewAppender(f, AppenderOptions{Replace: true, Rewrite: rewrite})
+	require.NoError(t, err)
+	appendMember(t, appender, "21x", "appended")
+	require.NoError(t, appender.Close())
+	assert.True(t, bytes.HasPrefix(f.data, data))
+	assert.Equal(t, 0, rewritten.Len())
+
+	// ...but if the archive must be rewritten, the new archive is passed to Rewrite instead.
+	f = &memFile{data: append([]byte(nil), data...)}
+	appender, err = NewAppender(f, AppenderOptions{Replace: true, Rewrite: rewrite})
+	require.NoError(t, err)
+	appendMember(t, appender, "3xx", "replaced")
+	require.NoError(t, appender.Close())
+	assert.Equal(t, data, f.data)
+	reader, err := NewReader(&rewritten)
+	require.NoError(t, err)
+	var hdr *Header
+	for i := 0; i < 3; i++ {
+		hdr, err = reader.Next()
+		require.NoError(t, err)
+	}
+	assert.Equal(t, "3xx", hdr.Name)
+	replaced, err := io.ReadAll(reader)
+	require.NoError(t, err)
+	assert.Equal(t, "replaced", string(replaced))
+}
+
+func TestAppendThin(t *testing.T) {
+	f := copyFile(t, "thin.a")
+	appender, err := NewAppender(f, AppenderOptions{})
+	require.NoError(t, err)
+	appendMember(t, appender, "objs/h.txt", "hello\n")
+	// The archive has a symbol table, which can't be regenerated without the existing members' data.
+	err = appender.Close()
+	assert.ErrorIs(t, err, ErrNoResolver)
+
+	f = copyFile(t, "thin.a")
+	appender, err = NewAppender(f, AppenderOptions{Resolver: DirResolver("./test_data")})
+	require.NoError(t, err)
+	appendMember(t, appender, "objs/h.txt", "hello\n")
+	require.NoError(t, appender.Close())
+	fi, err := f.Stat()
+	require.NoError(t, err)
+	archive, err := NewArchive(f, fi.Size())
+	require.NoError(t, err)
+	assert.True(t, archive.Thin())
+	require.Len(t, archive.Files, 4)
+	assert.Equal(t, "objs/h.txt", archive.Files[3].Name)
+	assert.Equal(t, "objs/add.o", archive.SymbolTable()["add"].Name)
+	assert.Equal(t, "objs/sub/a_long_object_name.o", archive.SymbolTable()["sub"].Name)
+}
+
+func TestAppendThinWithoutData(t *testing.T) {
+	// The archive is empty, so the Appender can only tell that it uses the GNU variant because it's
+	// thin. The data sections of thin archives' members aren't stored in the archive, so they needn't
+	// be written.
+	path := filepath.Join(t.TempDir(), "thin.a")
+	require.NoError(t, os.WriteFile(path, []byte(THIN_GLOBAL_HEADER), 0644))
+	f, err := os.OpenFile(path, os.O_RDWR, 0)
+	require.NoError(t, err)
+	defer f.Close()
+	appender, err := NewAppender(f, AppenderOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, GNU, appender.Variant())
+	require.NoError(t, appender.WriteHeader(&Header{Name: "objs/h.txt", Mode: 0644, Size: 6}))
+	require.NoError(t, appender.Close())
+
+	fi, err := f.Stat()
+	require.NoError(t, err)
+	archive, err := NewArchive(f, fi.Size())
+	require.NoError(t, err)
+	assert.True(t, archive.Thin())
+	require.Len(t, archive.Files, 1)
+	assert.Equal(t, "objs/h.txt", archive.Files[0].Name)
+	assert.Equal(t, int64(6), archive.Files[0].Size)
+}
@@ -0,0 +1,198 @@
+package ar
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+)
+
+// Archive provides random access to the members of an ar archive. Unlike Reader, which reads an
+// archive sequentially, Archive scans the archive's headers once when it is created and allows the
+// data sections of its members to be read independently of one another, in any order, and
+// concurrently (if the underlying io.ReaderAt supports concurrent use). Archive also implements
+// fs.FS, fs.ReadDirFS and fs.StatFS, presenting the archive's members as files in a read-only file
+// system.
+//
+// Example:
+//
+//	archive, err := ar.NewArchive(f, size)
+//	if err != nil {
+//		return err
+//	}
+//	for _, file := range archive.Files {
+//		r, err := file.Open()
+//		if err != nil {
+//			return err
+//		}
+//		io.Copy(&buf, r)
+//	}
+type Archive struct {
+	// Files is the list of members in the archive, in the order in which they appear in the archive.
+	// It does not include special members such as symbol tables and string tables.
+	Files []*File
+
+	// r is the underlying archive file.
+	r io.ReaderAt
+
+	// variant is the variant of the ar file format used by the archive.
+	variant Variant
+
+	// thin is true if the archive is a thin archive, whose members' data sections are stored in
+	// external files.
+	thin bool
+
+	// symbols is the archive's symbol table, or nil if the archive has no symbol table.
+	symbols []Symbol
+
+	// symbolTable maps the names of symbols in the archive's symbol table to the members that define
+	// them.
+	symbolTable map[string]*File
+
+	// coffLinkerMember is the archive's second linker member, or nil if the archive has none.
+	coffLinkerMember *COFFLinkerMember
+
+	// fsOnce guards the initialisation of fsEntries and fsChildren, which make up the file system view
+	// of the archive (see initFS).
+	fsOnce sync.Once
+
+	// fsEntries maps the paths of files and directories in the file system view of the archive to
+	// their entries.
+	fsEntries map[string]*fsEntry
+
+	// fsChildren maps the paths of directories in the file system view of the archive to their
+	// entries, sorted by name.
+	fsChildren map[string][]*fsEntry
+}
+
+// File is a member of an ar archive being read by an Archive.
+type File struct {
+	Header
+
+	// archive is the Archive that contains this member.
+	archive *Archive
+
+	// headerOffset and dataOffset are the byte offsets of the member's header and data section
+	// respectively, relative to the start of the archive file.
+	headerOffset, dataOffset int64
+
+	// index is the position of the member in the archive, counting special members such as symbol
+	// tables and string tables.
+	index int
+}
+
+// NewArchive returns an Archive reading from r, which is assumed to have the given size in bytes. It
+// returns an error if the archive is malformed.
+func NewArchive(r io.ReaderAt, size int64) (*Archive, error) {
+	rd, err := NewReader(io.NewSectionReader(r, 0, size))
+	if err != nil {
+		return nil, err
+	}
+	a := &Archive{
+		r:       r,
+		variant: rd.Variant(),
+		thin:    rd.Thin(),
+	}
+	byOffset := map[int64]*File{}
+	for {
+		hdr, err := rd.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if hdr.Size < 0 {
+			return nil, &ErrHeader{Offset: rd.headerOffset, Index: rd.index, Err: errors.New("negative size")}
+		}
+		if !a.thin && rd.dataOffset+hdr.Size > size {
+			return nil, &ErrFileName{Name: hdr.Name, Offset: rd.headerOffset, Index: rd.index, Err: io.ErrUnexpectedEOF}
+		}
+		f := &File{
+			Header:       *hdr,
+			archive:      a,
+			headerOffset: rd.headerOffset,
+			dataOffset:   rd.dataOffset,
+			index:        rd.index,
+		}
+		a.Files = append(a.Files, f)
+		byOffset[f.headerOffset] = f
+	}
+	if symbols := rd.Symbols(); symbols != nil {
+		a.symbols = symbols
+		a.symbolTable = map[string]*File{}
+		for _, symbol := range symbols {
+			f, ok := byOffset[symbol.Offset]
+			if !ok {
+				return nil, &ErrSymbolTable{Offset: rd.symbolTableOffset, Index: rd.symbolTableIndex, Err: fmt.Errorf("symbol '%s' refers to nonexistent member at offset %d", symbol.Name, symbol.Offset)}
+			}
+			if _, present := a.symbolTable[symbol.Name]; !present {
+				a.symbolTable[symbol.Name] = f
+			}
+		}
+	}
+	if lm := rd.COFFLinkerMember(); lm != nil {
+		for _, offset := range lm.Offsets {
+			if _, ok := byOffset[offset]; !ok {
+				return nil, &ErrSymbolTable{Offset: rd.symbolTableOffset, Index: rd.symbolTableIndex + 1, Err: fmt.Errorf("second linker member refers to nonexistent member at offset %d", offset)}
+			}
+		}
+		a.coffLinkerMember = lm
+	}
+	return a, nil
+}
+
+// Variant returns the ar file format variant used by the archive file.
+func (a *Archive) Variant() Variant {
+	return a.variant
+}
+
+// Thin returns whether the archive is a thin archive, in which the data sections of members are
+// stored in external files rather than in the archive itself. The data sections of thin archives'
+// members can't be opened with File.Open; use Reader with a Resolver to read them instead.
+func (a *Archive) Thin() bool {
+	return a.thin
+}
+
+// Symbols returns the entries in the archive's symbol table, in the order in which they appear in
+// the archive. It returns nil if the archive has no symbol table.
+func (a *Archive) Symbols() []Symbol {
+	return a.symbols
+}
+
+// SymbolTable returns a map of the names of symbols in the archive's symbol table to the archive
+// members that define them. If more than one member defines the same symbol, the map contains the
+// first of them. SymbolTable returns nil if the archive has no symbol table.
+func (a *Archive) SymbolTable() map[string]*File {
+	return a.symbolTable
+}
+
+// COFFLinkerMember returns the archive's second linker member, which archives produced by
+// Microsoft's tools (e.g. Windows import libraries) contain in addition to the symbol table returned
+// by Symbols. It returns nil if the archive has no second linker member.
+func (a *Archive) COFFLinkerMember() *COFFLinkerMember {
+	return a.coffLinkerMember
+}
+
+// Open returns an io.SectionReader that provides access to the member's data section. It may be
+// called any number of times, and the returned readers may be used concurrently with one another.
+// It returns an error if the archive is thin, since the member's data section is stored in an
+// external file.
+func (f *File) Open() (*io.SectionReader, error) {
+	if f.archive.thin {
+		return nil, &ErrFileName{Name: f.Name, Offset: f.headerOffset, Index: f.index, Err: errors.New("data section of thin archive member is stored externally")}
+	}
+	return io.NewSectionReader(f.archive.r, f.dataOffset, f.Size), nil
+}
+
+// HeaderOffset returns the byte offset of the member's header, relative to the start of the archive
+// file.
+func (f *File) HeaderOffset() int64 {
+	return f.headerOffset
+}
+
+// DataOffset returns the byte offset of the member's data section, relative to the start of the
+// archive file.
+func (f *File) DataOffset() int64 {
+	return f.dataOffset
+}
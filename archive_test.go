@@ -0,0 +1,115 @@
+package ar
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func openArchive(t *testing.T, path string) *Archive {
+	f, err := os.Open(path)
+	require.NoError(t, err)
+	t.Cleanup(func() { f.Close() })
+	fi, err := f.Stat()
+	require.NoError(t, err)
+	archive, err := NewArchive(f, fi.Size())
+	require.NoError(t, err)
+	return archive
+}
+
+func TestArchiveLongFilenames(t *testing.T) {
+	for _, tc := range []struct {
+		Description string
+		Variant     Variant
+		ArchivePath string
+	}{
+		{"BSD format", BSD, "./test_data/long_filenames_bsd.a"},
+		{"GNU format", GNU, "./test_data/long_filenames_gnu.a"},
+	} {
+		t.Run(tc.Description, func(t *testing.T) {
+			archive := openArchive(t, tc.ArchivePath)
+			assert.Equal(t, tc.Variant, archive.Variant())
+			require.Len(t, archive.Files, 20)
+			// Read the members in reverse order and concurrently, to prove that they're independent.
+			var wg sync.WaitGroup
+			for i := len(archive.Files); i >= 1; i-- {
+				i := i
+				f := archive.Files[i-1]
+				assert.Equal(t, fmt.Sprintf("%d%s", i, strings.Repeat("x", i-len(strconv.Itoa(i)))), f.Name)
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					r, err := f.Open()
+					require.NoError(t, err)
+					b, err := io.ReadAll(r)
+					require.NoError(t, err)
+					assert.Equal(t, fmt.Sprintf("The name of this file contains %d character(s).\n", i), string(b))
+				}()
+			}
+			wg.Wait()
+		})
+	}
+}
+
+func TestArchiveOffsets(t *testing.T) {
+	archive := openArchive(t, "./test_data/symbols_bsd.a")
+	require.Len(t, archive.Files, 2)
+	// llvm-ar stores all file names in BSD-variant archives at the start of the data section, padded
+	// with nulls so that the rest of the data section is aligned to 8 bytes.
+	assert.Equal(t, int64(128), archive.Files[0].HeaderOffset())
+	assert.Equal(t, int64(128+60+12), archive.Files[0].DataOffset())
+	assert.Equal(t, int64(936), archive.Files[1].HeaderOffset())
+	assert.Equal(t, int64(936+60+12), archive.Files[1].DataOffset())
+}
+
+func TestArchiveSymbolTable(t *testing.T) {
+	for _, path := range []string{"./test_data/symbols_gnu.a", "./test_data/symbols_bsd.a"} {
+		t.Run(path, func(t *testing.T) {
+			archive := openArchive(t, path)
+			require.Len(t, archive.Files, 2)
+			assert.Len(t, archive.Symbols(), 3)
+			assert.Equal(t, map[string]*File{
+				"add":     archive.Files[0],
+				"counter": archive.Files[0],
+				"sub":     archive.Files[1],
+			}, archive.SymbolTable())
+		})
+	}
+}
+
+func TestArchiveTruncated(t *testing.T) {
+	b, err := os.ReadFile("./test_data/hello.a")
+	require.NoError(t, err)
+	_, err = NewArchive(bytes.NewReader(b), int64(len(b)))
+	require.NoError(t, err)
+	_, err = NewArchive(bytes.NewReader(b[:len(b)-2]), int64(len(b)-2))
+	assert.ErrorIs(t, err, io.ErrUnexpectedEOF)
+}
+
+func TestArchiveNegativeSize(t *testing.T) {
+	b := []byte(GLOBAL_HEADER + fmt.Sprintf("%-16s%-12s%-6s%-6s%-8s%-10s`\n", "a.txt/", "0", "0", "0", "644", "-4"))
+	_, err := NewArchive(bytes.NewReader(b), int64(len(b)))
+	var headerErr *ErrHeader
+	assert.ErrorAs(t, err, &headerErr)
+}
+
+func TestArchiveThin(t *testing.T) {
+	archive := openArchive(t, "./test_data/thin.a")
+	assert.True(t, archive.Thin())
+	require.Len(t, archive.Files, 3)
+	assert.Equal(t, "objs/add.o", archive.Files[0].Name)
+	assert.Equal(t, "objs/sub/a_long_object_name.o", archive.Files[1].Name)
+	assert.Equal(t, "objs/h.txt", archive.Files[2].Name)
+	assert.Equal(t, int64(6), archive.Files[2].Size)
+	assert.Equal(t, archive.Files[1], archive.SymbolTable()["sub"])
+	_, err := archive.Files[0].Open()
+	assert.Error(t, err)
+}
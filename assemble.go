@@ -0,0 +1,285 @@
+package ar
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+)
+
+// member is an archive member that has yet to be written to an archive, along with its data section.
+type member struct {
+	hdr  Header
+	data *io.SectionReader
+
+	// symbols are the global symbols defined by the member, if they are already known; if symbols is
+	// nil, they are found by parsing data when a symbol table is generated.
+	symbols []string
+}
+
+// archiveMembers returns the members of the archive a, whose data sections are read directly from the
+// underlying archive file. The data sections of thin archives' members are only needed if a symbol
+// table is to be generated (i.e. if symbolIndex is true), in which case they are opened with resolver
+// and copied into s; otherwise, the Writer discards them, so any data of the correct size will do.
+func archiveMembers(a *Archive, symbolIndex bool, resolver Resolver, s *spool) ([]member, error) {
+	members := make([]member, len(a.Files))
+	for i, f := range a.Files {
+		members[i] = member{hdr: f.Header}
+		if !a.Thin() {
+			members[i].data, _ = f.Open()
+			continue
+		}
+		if !symbolIndex {
+			members[i].data = io.NewSectionReader(zeroReaderAt{}, 0, f.Size)
+			continue
+		}
+		if resolver == nil {
+			return nil, &ErrFileName{Name: f.Name, Offset: f.headerOffset, Index: f.index, Err: ErrNoResolver}
+		}
+		off := s.Len()
+		if err := resolveInto(s, resolver, f); err != nil {
+			return nil, &ErrFileName{Name: f.Name, Offset: f.headerOffset, Index: f.index, Err: err}
+		}
+		members[i].data = io.NewSectionReader(s.ReaderAt(), off, f.Size)
+	}
+	return members, nil
+}
+
+// resolveInto copies the data section of a member of a thin archive, opened with resolver, into s.
+func resolveInto(s *spool, resolver Resolver, f *File) error {
+	r, err := resolver(f.Name)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	n, err := io.Copy(s, io.LimitReader(r, f.Size))
+	if err != nil {
+		return err
+	}
+	if n < f.Size {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+
+// zeroReaderAt is an io.ReaderAt that reads an unlimited number of zero bytes.
+type zeroReaderAt struct{}
+
+func (zeroReaderAt) ReadAt(b []byte, off int64) (int, error) {
+	for i := range b {
+		b[i] = 0
+	}
+	return len(b), nil
+}
+
+// bufferHeader begins a new archive member in a buffered Writer.
+func (aw *Writer) bufferHeader(hdr *Header) error {
+	if aw.closed {
+		return errors.New("ar: write to closed writer")
+	}
+	if aw.nb > 0 && !aw.discardsData() {
+		return fmt.Errorf("ar: missed writing %d bytes", aw.nb)
+	}
+	if len(hdr.Name) == 0 {
+		return errors.New("ar: empty file name")
+	}
+	aw.members = append(aw.members, bufferedMember{
+		hdr: *hdr,
+		off: aw.spool.Len(),
+	})
+	aw.nb = hdr.Size
+	return nil
+}
+
+// discardsData returns whether a buffered Writer discards members' data sections rather than holding
+// them until the archive is written, which is the case for thin archives without a symbol table.
+func (aw *Writer) discardsData() bool {
+	return aw.opts.Thin && !aw.opts.SymbolIndex
+}
+
+// flush writes the archive members held in memory by a buffered Writer to the underlying io.Writer.
+func (aw *Writer) flush() error {
+	data := aw.spool.ReaderAt()
+	members := make([]member, len(aw.members))
+	for i, m := range aw.members {
+		members[i] = member{
+			hdr:  m.hdr,
+			data: io.NewSectionReader(data, m.off, m.hdr.Size),
+		}
+		if aw.discardsData() {
+			members[i].data = io.NewSectionReader(zeroReaderAt{}, 0, m.hdr.Size)
+		}
+	}
+	out := newWriter(aw.w, aw.variant, aw.opts)
+	if err := out.writeArchive(members, aw.longNames); err != nil {
+		return err
+	}
+	return out.Close()
+}
+
+// writeArchive writes the given members to the underlying io.Writer, preceded by a symbol table if
+// the SymbolIndex option is set and, for GNU-variant archives, a string table containing the given
+// long file names plus any others needed by the members. It must be called before anything else has
+// been written to the archive.
+func (aw *Writer) writeArchive(members []member, longNames []string) error {
+	var names []string
+	if aw.variant == GNU {
+		seen := map[string]bool{}
+		add := func(name string) {
+			if !seen[name] {
+				seen[name] = true
+				names = append(names, name)
+			}
+		}
+		for _, name := range longNames {
+			add(name)
+		}
+		for _, m := range members {
+			if aw.longName(m.hdr.Name) {
+				add(m.hdr.Name)
+			}
+		}
+		if aw.opts.Deterministic {
+			names = sortedUnique(names)
+		}
+	}
+	if aw.opts.SymbolIndex {
+		if err := aw.writeSymbolIndex(members, names); err != nil {
+			return err
+		}
+	}
+	if len(names) > 0 {
+		if err := aw.WriteStringTable(names); err != nil {
+			return err
+		}
+	}
+	for _, m := range members {
+		hdr := m.hdr
+		if err := aw.WriteHeader(&hdr); err != nil {
+			return err
+		}
+		if _, err := io.Copy(aw, io.NewSectionReader(m.data, 0, m.data.Size())); err != nil {
+			return fmt.Errorf("ar: write archive member '%s': %w", m.hdr.Name, err)
+		}
+	}
+	return nil
+}
+
+// writeSymbolIndex writes a symbol table listing the global symbols defined by the object files
+// among the given members (or the symbols already known to be defined by them), which are to be
+// written after the symbol table and a string table containing the given long file names.
+func (aw *Writer) writeSymbolIndex(members []member, longNames []string) error {
+	defined := make([][]string, len(members))
+	for i, m := range members {
+		if m.symbols != nil {
+			defined[i] = m.symbols
+			continue
+		}
+		names, err := objectSymbols(m.data)
+		if err != nil {
+			return &ErrSymbolTable{Err: fmt.Errorf("archive member '%s': %w", m.hdr.Name, err)}
+		}
+		defined[i] = names
+	}
+	// The offsets of the members' headers depend on the size of the symbol table, which in turn
+	// depends on whether the offsets can be represented in 32 bits, so the symbol table needs to be
+	// encoded repeatedly until its size stops changing.
+	var symbols []Symbol
+	var lm *COFFLinkerMember
+	for tableSize := int64(0); ; {
+		symbols = symbols[:0]
+		offset := int64(len(GLOBAL_HEADER)) + tableSize + aw.stringTableSize(longNames)
+		if aw.coff {
+			offset += aw.coffLinkerMemberSize(len(members), defined)
+		}
+		lm = &COFFLinkerMember{}
+		for i, m := range members {
+			lm.Offsets = append(lm.Offsets, offset)
+			for _, name := range defined[i] {
+				symbols = append(symbols, Symbol{Name: name, Offset: offset})
+			}
+			offset += aw.memberSize(&m.hdr)
+		}
+		var name string
+		var data []byte
+		if aw.variant == GNU {
+			name, data = encodeGNUSymbolTable(symbols)
+		} else {
+			name, data = encodeBSDSymbolTable(symbols)
+		}
+		size := aw.memberSize(&Header{Name: name, Size: int64(len(data))})
+		if size == tableSize {
+			break
+		}
+		tableSize = size
+	}
+	if aw.coff {
+		return aw.writeCOFFLinkerMembers(symbols, lm)
+	}
+	// Like GNU ar, omit the symbol table from GNU-variant archives if it would be empty. Linkers for
+	// BSD-variant archives (e.g. Apple's ld64) expect a symbol table to be present regardless.
+	if aw.variant == GNU && len(symbols) == 0 {
+		return nil
+	}
+	return aw.WriteSymbolTable(symbols)
+}
+
+// writeCOFFLinkerMembers writes the first and second linker members of an archive in the format
+// produced by Microsoft's tools: the first is a GNU-style symbol table listing the given symbols,
+// and the second lists the same symbols sorted by name. lm contains the offsets of the archive's
+// members. Unlike GNU ar, Microsoft's tools write the linker members even if there are no symbols.
+func (aw *Writer) writeCOFFLinkerMembers(symbols []Symbol, lm *COFFLinkerMember) error {
+	if err := aw.WriteSymbolTable(symbols); err != nil {
+		return err
+	}
+	lm.Symbols = append([]Symbol{}, symbols...)
+	sort.SliceStable(lm.Symbols, func(i, j int) bool { return lm.Symbols[i].Name < lm.Symbols[j].Name })
+	data := encodeCOFFLinkerMember(lm)
+	if err := aw.WriteHeader(&Header{Name: "/", Size: int64(len(data))}); err != nil {
+		return err
+	}
+	_, err := aw.Write(data)
+	return err
+}
+
+// coffLinkerMemberSize returns the number of bytes that the second linker member will occupy in an
+// archive with the given number of members, which define the given symbols.
+func (aw *Writer) coffLinkerMemberSize(members int, defined [][]string) int64 {
+	size := 4 + 4*members + 4
+	for _, names := range defined {
+		for _, name := range names {
+			size += 2 + len(name) + 1
+		}
+	}
+	return aw.memberSize(&Header{Name: "/", Size: int64(size)})
+}
+
+// stringTableSize returns the number of bytes that a string table containing the given file names
+// will occupy in the archive, including its header.
+func (aw *Writer) stringTableSize(filenames []string) int64 {
+	if len(filenames) == 0 {
+		return 0
+	}
+	var size int64
+	for _, filename := range filenames {
+		if aw.coff {
+			size += int64(len(filename)) + 1
+		} else {
+			size += int64(len(filename)) + 2
+		}
+	}
+	return aw.memberSize(&Header{Name: "//", Size: size})
+}
+
+// memberSize returns the number of bytes that the archive member with the given header will occupy
+// in the archive, including its header, any file name prepended to its data section, and padding.
+func (aw *Writer) memberSize(hdr *Header) int64 {
+	if aw.opts.Thin && !specialGNUName(hdr.Name) {
+		return HEADER_BYTE_SIZE
+	}
+	size := hdr.Size
+	if aw.variant == BSD && bsdLongName(hdr.Name) {
+		size += int64(len(hdr.Name) + len(hdr.Name)%2)
+	}
+	return HEADER_BYTE_SIZE + size + size%2
+}
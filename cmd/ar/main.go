@@ -0,0 +1,520 @@
+// Command ar creates, modifies and extracts from ar archives. It implements the most commonly used
+// operations of GNU ar, using only the github.com/please-build/ar package.
+//
+// Usage:
+//
+//	ar [--format=gnu|bsd] [-]<operation>[modifiers...] <archive> [member...]
+//	ar [--format=gnu|bsd] -M[cDs] < script
+//
+// Operations:
+//
+//	C  convert the archive to the variant of the ar file format selected by --format
+//	d  delete the named members from the archive
+//	m  move the named members to the end of the archive
+//	p  print the contents of the named members (or all members) to standard output
+//	q  quickly append the named files to the archive
+//	r  insert the named files into the archive, replacing existing members with the same names
+//	s  write a symbol table to the archive (this may also be used as a modifier)
+//	t  list the named members (or all members) of the archive
+//	x  extract the named members (or all members) from the archive into the current directory
+//
+// Modifiers:
+//
+//	c  don't warn if the archive has to be created
+//	D  use zero for timestamps, owner and group IDs, and 0644 for file modes
+//	u  only replace existing members that are older than the named files
+//	v  be verbose
+//
+// With -M, ar reads an MRI script from standard input (see ar.MRIScript). Like GNU ar, archives saved
+// by MRI scripts are always given a symbol table.
+//
+// The --format option selects the variant of the ar file format used when creating a new archive;
+// existing archives keep the variant they already use, unless they are converted with C.
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/please-build/ar"
+)
+
+const usage = "usage: ar [--format=gnu|bsd] [-]{Cdmpqrst}[cDuv] archive [member...]\n       ar [--format=gnu|bsd] -M[cDs] < script"
+
+// options are the options given on the command line.
+type options struct {
+	// op is the operation to perform.
+	op byte
+
+	// The modifiers.
+	create, deterministic, symbolIndex, update, verbose bool
+
+	// variant is the variant of the ar file format to use when creating an archive.
+	variant ar.Variant
+
+	// archive is the path to the archive file.
+	archive string
+
+	// members are the names of the archive members, or paths to files, to operate on.
+	members []string
+}
+
+func main() {
+	if err := run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr); err != nil {
+		fmt.Fprintf(os.Stderr, "ar: %s\n", strings.TrimPrefix(err.Error(), "ar: "))
+		os.Exit(1)
+	}
+}
+
+// run runs the command with the given command line arguments.
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	opts, err := parseArgs(args)
+	if err != nil {
+		return err
+	}
+	switch opts.op {
+	case 'M':
+		return mri(opts, stdin)
+	case 't', 'p', 'x':
+		return read(opts, stdout)
+	case 'r', 'q':
+		return insert(opts, stdout, stderr)
+	case 'C':
+		return convert(opts)
+	default:
+		return edit(opts, stdout)
+	}
+}
+
+// parseArgs parses the given command line arguments.
+func parseArgs(args []string) (*options, error) {
+	opts := &options{variant: ar.GNU}
+	if runtime.GOOS == "darwin" {
+		opts.variant = ar.BSD
+	}
+	var positional []string
+	for _, arg := range args {
+		switch {
+		case strings.HasPrefix(arg, "--format="):
+			switch format := strings.TrimPrefix(arg, "--format="); format {
+			case "gnu":
+				opts.variant = ar.GNU
+			case "bsd":
+				opts.variant = ar.BSD
+			default:
+				return nil, fmt.Errorf("unknown archive format '%s'", format)
+			}
+		case arg == "--help":
+			return nil, errors.New(usage)
+		default:
+			positional = append(positional, arg)
+		}
+	}
+	if len(positional) == 0 {
+		return nil, errors.New(usage)
+	}
+	for _, c := range strings.TrimPrefix(positional[0], "-") {
+		switch c {
+		case 'C', 'd', 'm', 'p', 'q', 'r', 't', 'x', 'M':
+			if opts.op != 0 {
+				return nil, errors.New("two different operation options specified")
+			}
+			opts.op = byte(c)
+		case 's':
+			opts.symbolIndex = true
+		case 'c':
+			opts.create = true
+		case 'D':
+			opts.deterministic = true
+		case 'u':
+			opts.update = true
+		case 'v':
+			opts.verbose = true
+		default:
+			return nil, fmt.Errorf("unknown option '%c'\n%s", c, usage)
+		}
+	}
+	if opts.op == 0 {
+		if !opts.symbolIndex {
+			return nil, errors.New("no operation specified")
+		}
+		opts.op = 's'
+	}
+	if opts.op == 'M' {
+		if len(positional) > 1 {
+			return nil, errors.New(usage)
+		}
+		return opts, nil
+	}
+	if len(positional) < 2 || (opts.op == 'C' && len(positional) > 2) {
+		return nil, errors.New(usage)
+	}
+	opts.archive = positional[1]
+	opts.members = positional[2:]
+	return opts, nil
+}
+
+// selection keeps track of which of the archive members named on the command line have been found.
+type selection struct {
+	names map[string]bool
+}
+
+func newSelection(members []string) *selection {
+	s := &selection{names: map[string]bool{}}
+	for _, name := range members {
+		s.names[name] = false
+	}
+	return s
+}
+
+// selects returns whether the archive member with the given name has been selected, which is the case
+// if it was named on the command line or if no members were named.
+func (s *selection) selects(name string) bool {
+	if len(s.names) == 0 {
+		return true
+	}
+	if _, ok := s.names[name]; !ok {
+		return false
+	}
+	s.names[name] = true
+	return true
+}
+
+// missing returns an error if any of the archive members named on the command line weren't found.
+func (s *selection) missing(archive string) error {
+	for name, found := range s.names {
+		if !found {
+			return fmt.Errorf("no entry %s in archive %s", name, archive)
+		}
+	}
+	return nil
+}
+
+// openArchive opens the archive file at the given path.
+func openArchive(path string) (*ar.Archive, *os.File, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, nil, err
+	}
+	a, err := ar.NewArchive(f, fi.Size())
+	if err != nil {
+		f.Close()
+		return nil, nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return a, f, nil
+}
+
+// read performs the t, p and x operations, which read members from the archive.
+func read(opts *options, stdout io.Writer) error {
+	a, f, err := openArchive(opts.archive)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	resolver := ar.DirResolver(filepath.Dir(opts.archive))
+	sel := newSelection(opts.members)
+	for _, file := range a.Files {
+		if !sel.selects(file.Name) {
+			continue
+		}
+		switch opts.op {
+		case 't':
+			if opts.verbose {
+				fmt.Fprintf(stdout, "%s %d/%d %6d %s %s\n", file.FileInfo().Mode().Perm().String()[1:], file.Uid, file.Gid, file.Size, file.ModTime.Format("Jan _2 15:04 2006"), file.Name)
+			} else {
+				fmt.Fprintln(stdout, file.Name)
+			}
+			continue
+		case 'p':
+			if opts.verbose {
+				fmt.Fprintf(stdout, "\n<%s>\n\n", file.Name)
+			}
+			err = copyMember(stdout, a, file, resolver)
+		case 'x':
+			if opts.verbose {
+				fmt.Fprintf(stdout, "x - %s\n", file.Name)
+			}
+			err = extract(a, file, resolver)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return sel.missing(opts.archive)
+}
+
+// copyMember copies the data section of an archive member to w.
+func copyMember(w io.Writer, a *ar.Archive, file *ar.File, resolver ar.Resolver) error {
+	var r io.Reader
+	if a.Thin() {
+		rc, err := resolver(file.Name)
+		if err != nil {
+			return err
+		}
+		defer rc.Close()
+		r = io.LimitReader(rc, file.Size)
+	} else {
+		sr, err := file.Open()
+		if err != nil {
+			return err
+		}
+		r = sr
+	}
+	_, err := io.Copy(w, r)
+	return err
+}
+
+// extract extracts an archive member into the current directory.
+func extract(a *ar.Archive, file *ar.File, resolver ar.Resolver) error {
+	if strings.ContainsAny(file.Name, `/\`) || file.Name == "." || file.Name == ".." {
+		return fmt.Errorf("%s: refusing to extract member with illegal file name", file.Name)
+	}
+	f, err := os.OpenFile(file.Name, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, file.FileInfo().Mode().Perm())
+	if err != nil {
+		return err
+	}
+	if err := copyMember(f, a, file, resolver); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// insert performs the r and q operations, which insert files into the archive.
+func insert(opts *options, stdout, stderr io.Writer) error {
+	f, err := os.OpenFile(opts.archive, os.O_RDWR, 0)
+	if errors.Is(err, os.ErrNotExist) {
+		if !opts.create {
+			fmt.Fprintf(stderr, "ar: creating %s\n", opts.archive)
+		}
+		f, err = os.OpenFile(opts.archive, os.O_RDWR|os.O_CREATE, 0644)
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	appender, err := ar.NewAppender(f, ar.AppenderOptions{
+		WriterOptions: ar.WriterOptions{
+			SymbolIndex:   opts.symbolIndex,
+			Deterministic: opts.deterministic,
+		},
+		Variant:  opts.variant,
+		Replace:  opts.op == 'r',
+		Resolver: ar.DirResolver(filepath.Dir(opts.archive)),
+		// Like d, m and s, rewrite the entire archive (if necessary) via a temporary file, so that it is
+		// left intact if anything goes wrong.
+		Rewrite: func(write func(io.Writer) error) error {
+			return rewrite(opts.archive, write)
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("%s: %w", opts.archive, err)
+	}
+	existing, thin, err := existingMembers(f)
+	if err != nil {
+		return err
+	}
+	for _, path := range opts.members {
+		fi, err := os.Stat(path)
+		if err != nil {
+			return err
+		}
+		hdr, err := ar.FileInfoHeader(fi)
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		// Like GNU ar, name the members of thin archives after the paths to their files relative to the
+		// directory containing the archive, since that's where their data sections are read from.
+		if thin {
+			if hdr.Name, err = thinMemberName(opts.archive, path); err != nil {
+				return err
+			}
+		}
+		member, replacing := existing[hdr.Name]
+		replacing = replacing && opts.op == 'r'
+		// Archive members' modification times only have a resolution of one second.
+		if opts.update && replacing && !hdr.ModTime.Truncate(time.Second).After(member.ModTime) {
+			continue
+		}
+		if opts.verbose {
+			if replacing {
+				fmt.Fprintf(stdout, "r - %s\n", path)
+			} else {
+				fmt.Fprintf(stdout, "a - %s\n", path)
+			}
+		}
+		if err := appendFile(appender, hdr, path); err != nil {
+			return err
+		}
+	}
+	return appender.Close()
+}
+
+// existingMembers returns the headers of the members of the archive in f, indexed by name, and
+// whether the archive is thin. If more than one member has the same name, the first is returned.
+func existingMembers(f *os.File) (map[string]*ar.Header, bool, error) {
+	fi, err := f.Stat()
+	if err != nil {
+		return nil, false, err
+	}
+	members := map[string]*ar.Header{}
+	if fi.Size() == 0 {
+		return members, false, nil
+	}
+	a, err := ar.NewArchive(f, fi.Size())
+	if err != nil {
+		return nil, false, err
+	}
+	for _, file := range a.Files {
+		if _, present := members[file.Name]; !present {
+			members[file.Name] = &file.Header
+		}
+	}
+	return members, a.Thin(), nil
+}
+
+// thinMemberName returns the name of the member of the thin archive at archivePath whose data section
+// is stored in the file at path.
+func thinMemberName(archivePath, path string) (string, error) {
+	dir, err := filepath.Abs(filepath.Dir(archivePath))
+	if err != nil {
+		return "", err
+	}
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+	name, err := filepath.Rel(dir, abs)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", path, err)
+	}
+	return filepath.ToSlash(name), nil
+}
+
+// appendFile appends the file at the given path to the archive, as a member with the given header.
+func appendFile(appender *ar.Appender, hdr *ar.Header, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if err := appender.WriteHeader(hdr); err != nil {
+		return err
+	}
+	if _, err := io.CopyN(appender, f, hdr.Size); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	return nil
+}
+
+// edit performs the d, m and s operations, which rewrite the archive.
+func edit(opts *options, stdout io.Writer) error {
+	a, f, err := openArchive(opts.archive)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	names := map[string]bool{}
+	for _, file := range a.Files {
+		names[file.Name] = true
+	}
+	var ops []ar.EditOp
+	for _, name := range opts.members {
+		switch opts.op {
+		case 'd':
+			// Like GNU ar, silently ignore members that don't exist.
+			if !names[name] {
+				continue
+			}
+			if opts.verbose {
+				fmt.Fprintf(stdout, "d - %s\n", name)
+			}
+			ops = append(ops, ar.DeleteMember(name))
+		case 'm':
+			if !names[name] {
+				return fmt.Errorf("no entry %s in archive %s", name, opts.archive)
+			}
+			if opts.verbose {
+				fmt.Fprintf(stdout, "m - %s\n", name)
+			}
+			ops = append(ops, ar.MoveMemberToEnd(name))
+		}
+	}
+	return rewrite(opts.archive, func(w io.Writer) error {
+		return ar.Edit(w, a, ops, ar.EditOptions{
+			WriterOptions: ar.WriterOptions{
+				SymbolIndex:   opts.symbolIndex,
+				Deterministic: opts.deterministic,
+			},
+			Resolver: ar.DirResolver(filepath.Dir(opts.archive)),
+		})
+	})
+}
+
+// convert performs the C operation, which converts the archive to another variant of the ar file
+// format.
+func convert(opts *options) error {
+	a, f, err := openArchive(opts.archive)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return rewrite(opts.archive, func(w io.Writer) error {
+		return ar.Convert(w, a, opts.variant, ar.WriterOptions{
+			SymbolIndex:   opts.symbolIndex,
+			Deterministic: opts.deterministic,
+		})
+	})
+}
+
+// rewrite replaces the file at the given path with the output of write, which is written to a
+// temporary file that is renamed over the original file if write succeeds.
+func rewrite(path string, write func(io.Writer) error) error {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".ar-")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if err := write(tmp); err != nil {
+		tmp.Close()
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	if err := tmp.Chmod(fi.Mode().Perm()); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// mri runs an MRI script read from r.
+func mri(opts *options, r io.Reader) error {
+	script, err := ar.ParseMRIScript(r)
+	if err != nil {
+		return err
+	}
+	return script.Run(ar.MRIOptions{
+		WriterOptions: ar.WriterOptions{
+			SymbolIndex:   true,
+			Deterministic: opts.deterministic,
+		},
+		Variant: opts.variant,
+	})
+}
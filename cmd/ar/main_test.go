@@ -0,0 +1,269 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/please-build/ar"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// arCmd runs the command with the given arguments in dir, returning its standard output.
+func arCmd(t *testing.T, dir string, args ...string) (string, error) {
+	return arCmdWithInput(t, dir, "", args...)
+}
+
+// arCmdWithInput runs the command with the given arguments and standard input in dir, returning its
+// standard output.
+func arCmdWithInput(t *testing.T, dir, stdin string, args ...string) (string, error) {
+	wd, err := os.Getwd()
+	require.NoError(t, err)
+	require.NoError(t, os.Chdir(dir))
+	defer os.Chdir(wd)
+	var stdout, stderr bytes.Buffer
+	err = run(args, strings.NewReader(stdin), &stdout, &stderr)
+	return stdout.String(), err
+}
+
+// writeFiles writes files with the given names to dir, each containing its own name.
+func writeFiles(t *testing.T, dir string, names ...string) {
+	for _, name := range names {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(name+"\n"), 0644))
+	}
+}
+
+func TestCreateAndList(t *testing.T) {
+	for _, tc := range []struct {
+		Format  string
+		Variant ar.Variant
+	}{
+		{"gnu", ar.GNU},
+		{"bsd", ar.BSD},
+	} {
+		t.Run(tc.Format, func(t *testing.T) {
+			dir := t.TempDir()
+			writeFiles(t, dir, "a.txt", "a_long_file_name.txt")
+			out, err := arCmd(t, dir, "--format="+tc.Format, "rcv", "lib.a", "a.txt", "a_long_file_name.txt")
+			require.NoError(t, err)
+			assert.Equal(t, "a - a.txt\na - a_long_file_name.txt\n", out)
+
+			f, err := os.Open(filepath.Join(dir, "lib.a"))
+			require.NoError(t, err)
+			defer f.Close()
+			reader, err := ar.NewReader(f)
+			require.NoError(t, err)
+			_, err = reader.Next()
+			require.NoError(t, err)
+			assert.Equal(t, tc.Variant, reader.Variant())
+
+			out, err = arCmd(t, dir, "t", "lib.a")
+			require.NoError(t, err)
+			assert.Equal(t, "a.txt\na_long_file_name.txt\n", out)
+		})
+	}
+}
+
+func TestDeterministic(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, "a.txt")
+	_, err := arCmd(t, dir, "rcD", "lib.a", "a.txt")
+	require.NoError(t, err)
+	out, err := arCmd(t, dir, "tv", "lib.a")
+	require.NoError(t, err)
+	assert.Equal(t, "rw-r--r-- 0/0      6 "+ar.Epoch.Local().Format("Jan _2 15:04 2006")+" a.txt\n", out)
+}
+
+func TestPrintAndExtract(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, "a.txt", "b.txt")
+	_, err := arCmd(t, dir, "qc", "lib.a", "a.txt", "b.txt")
+	require.NoError(t, err)
+
+	out, err := arCmd(t, dir, "p", "lib.a")
+	require.NoError(t, err)
+	assert.Equal(t, "a.txt\nb.txt\n", out)
+	out, err = arCmd(t, dir, "pv", "lib.a", "b.txt")
+	require.NoError(t, err)
+	assert.Equal(t, "\n<b.txt>\n\nb.txt\n", out)
+
+	extractDir := t.TempDir()
+	out, err = arCmd(t, extractDir, "xv", filepath.Join(dir, "lib.a"), "b.txt")
+	require.NoError(t, err)
+	assert.Equal(t, "x - b.txt\n", out)
+	data, err := os.ReadFile(filepath.Join(extractDir, "b.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, "b.txt\n", string(data))
+	_, err = os.Stat(filepath.Join(extractDir, "a.txt"))
+	assert.True(t, os.IsNotExist(err))
+
+	_, err = arCmd(t, dir, "t", "lib.a", "c.txt")
+	assert.EqualError(t, err, "no entry c.txt in archive lib.a")
+}
+
+func TestReplaceDeleteAndMove(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, "a.txt", "b.txt", "c.txt")
+	_, err := arCmd(t, dir, "qc", "lib.a", "a.txt", "b.txt", "c.txt")
+	require.NoError(t, err)
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "b.txt"), []byte("replaced\n"), 0644))
+	out, err := arCmd(t, dir, "rv", "lib.a", "b.txt")
+	require.NoError(t, err)
+	assert.Equal(t, "r - b.txt\n", out)
+	out, err = arCmd(t, dir, "p", "lib.a", "b.txt")
+	require.NoError(t, err)
+	assert.Equal(t, "replaced\n", out)
+
+	out, err = arCmd(t, dir, "mv", "lib.a", "a.txt")
+	require.NoError(t, err)
+	assert.Equal(t, "m - a.txt\n", out)
+	out, err = arCmd(t, dir, "dv", "lib.a", "c.txt", "d.txt")
+	require.NoError(t, err)
+	assert.Equal(t, "d - c.txt\n", out)
+	out, err = arCmd(t, dir, "t", "lib.a")
+	require.NoError(t, err)
+	assert.Equal(t, "b.txt\na.txt\n", out)
+}
+
+func TestReplaceViaTemporaryFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "lib.a")
+	writeFiles(t, dir, "a.txt", "b.txt")
+	_, err := arCmd(t, dir, "qc", "lib.a", "a.txt")
+	require.NoError(t, err)
+	before, err := os.Stat(path)
+	require.NoError(t, err)
+
+	// Appending to the archive updates the archive file in place...
+	_, err = arCmd(t, dir, "q", "lib.a", "b.txt")
+	require.NoError(t, err)
+	after, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.True(t, os.SameFile(before, after))
+
+	// ...but replacing a member rewrites the archive to a temporary file, which replaces it.
+	_, err = arCmd(t, dir, "r", "lib.a", "a.txt")
+	require.NoError(t, err)
+	replaced, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.False(t, os.SameFile(after, replaced))
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Len(t, entries, 3)
+	out, err := arCmd(t, dir, "t", "lib.a")
+	require.NoError(t, err)
+	assert.Equal(t, "a.txt\nb.txt\n", out)
+}
+
+func TestReplaceInThinArchive(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "lib"), 0755))
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "sub"), 0755))
+	writeFiles(t, dir, "lib/a.txt", "sub/c.txt")
+	f, err := os.Create(filepath.Join(dir, "lib", "thin.a"))
+	require.NoError(t, err)
+	writer := ar.NewWriterWithOptions(f, ar.GNU, ar.WriterOptions{Thin: true})
+	require.NoError(t, writer.WriteHeader(&ar.Header{Name: "a.txt", Mode: 0644, Size: 10}))
+	require.NoError(t, writer.Close())
+	require.NoError(t, f.Close())
+
+	// Members of thin archives are named after their files' paths relative to the archive, so that
+	// their data sections can be found.
+	_, err = arCmd(t, filepath.Join(dir, "lib"), "r", "thin.a", "../sub/c.txt")
+	require.NoError(t, err)
+	_, err = arCmd(t, dir, "r", "lib/thin.a", "sub/c.txt", "lib/a.txt")
+	require.NoError(t, err)
+	out, err := arCmd(t, dir, "t", "lib/thin.a")
+	require.NoError(t, err)
+	assert.Equal(t, "a.txt\n../sub/c.txt\n", out)
+	out, err = arCmd(t, dir, "p", "lib/thin.a")
+	require.NoError(t, err)
+	assert.Equal(t, "lib/a.txt\nsub/c.txt\n", out)
+}
+
+func TestUpdate(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, "a.txt")
+	_, err := arCmd(t, dir, "rc", "lib.a", "a.txt")
+	require.NoError(t, err)
+	// a.txt hasn't changed since it was added to the archive, so it shouldn't be replaced.
+	out, err := arCmd(t, dir, "ruv", "lib.a", "a.txt")
+	require.NoError(t, err)
+	assert.Equal(t, "", out)
+
+	// Once a.txt is newer than the archive member, it should be replaced.
+	later := time.Now().Add(time.Hour)
+	require.NoError(t, os.Chtimes(filepath.Join(dir, "a.txt"), later, later))
+	out, err = arCmd(t, dir, "ruv", "lib.a", "a.txt")
+	require.NoError(t, err)
+	assert.Equal(t, "r - a.txt\n", out)
+}
+
+func TestConvert(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, "a.txt", "a_long_file_name.txt")
+	_, err := arCmd(t, dir, "--format=gnu", "rc", "lib.a", "a.txt", "a_long_file_name.txt")
+	require.NoError(t, err)
+	for _, tc := range []struct {
+		Format  string
+		Variant ar.Variant
+	}{
+		{"bsd", ar.BSD},
+		{"gnu", ar.GNU},
+	} {
+		_, err := arCmd(t, dir, "--format="+tc.Format, "C", "lib.a")
+		require.NoError(t, err)
+		f, err := os.Open(filepath.Join(dir, "lib.a"))
+		require.NoError(t, err)
+		reader, err := ar.NewReader(f)
+		require.NoError(t, err)
+		_, err = reader.Next()
+		require.NoError(t, err)
+		assert.Equal(t, tc.Variant, reader.Variant())
+		f.Close()
+		out, err := arCmd(t, dir, "p", "lib.a")
+		require.NoError(t, err)
+		assert.Equal(t, "a.txt\na_long_file_name.txt\n", out)
+	}
+}
+
+func TestInvalidArguments(t *testing.T) {
+	for _, args := range [][]string{
+		{"lib.a"},
+		{"rt", "lib.a"},
+		{"rz", "lib.a"},
+		{"v", "lib.a"},
+		{"--format=coff", "r", "lib.a"},
+		{"-M", "lib.a"},
+		{"C", "lib.a", "a.txt"},
+	} {
+		t.Run(strings.Join(args, " "), func(t *testing.T) {
+			_, err := parseArgs(args)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestMRIScript(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, "a.txt", "b.txt", "c.txt")
+	_, err := arCmd(t, dir, "qc", "liba.a", "a.txt", "b.txt")
+	require.NoError(t, err)
+	script := `CREATE libc.a
+ADDLIB liba.a
+ADDMOD c.txt
+DELETE a.txt
+SAVE
+END
+`
+	_, err = arCmdWithInput(t, dir, script, "-M")
+	require.NoError(t, err)
+	out, err := arCmd(t, dir, "t", "libc.a")
+	require.NoError(t, err)
+	assert.Equal(t, "b.txt\nc.txt\n", out)
+}
@@ -0,0 +1,76 @@
+// Command ranlib generates a symbol table for each of the given ar archives, replacing any existing
+// symbol table. It uses only the github.com/please-build/ar package.
+//
+// Usage:
+//
+//	ranlib archive...
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/please-build/ar"
+)
+
+const usage = "usage: ranlib archive..."
+
+func main() {
+	if err := run(os.Args[1:]); err != nil {
+		fmt.Fprintf(os.Stderr, "ranlib: %s\n", strings.TrimPrefix(err.Error(), "ar: "))
+		os.Exit(1)
+	}
+}
+
+// run runs the command with the given command line arguments.
+func run(args []string) error {
+	if len(args) == 0 {
+		return errors.New(usage)
+	}
+	for _, arg := range args {
+		if strings.HasPrefix(arg, "-") {
+			return fmt.Errorf("unknown option '%s'\n%s", arg, usage)
+		}
+	}
+	for _, path := range args {
+		if err := ranlib(path); err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+	}
+	return nil
+}
+
+// ranlib replaces the archive at the given path with a copy that has a freshly generated symbol
+// table. The copy is written to a temporary file, which is renamed over the original archive if
+// successful.
+func ranlib(path string) error {
+	in, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	fi, err := in.Stat()
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".ranlib-")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if err := ar.Ranlib(in, tmp); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(fi.Mode().Perm()); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/please-build/ar"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeArchive writes an archive of the given variant containing a single text file to dir,
+// returning its path.
+func writeArchive(t *testing.T, dir string, variant ar.Variant) string {
+	var buf bytes.Buffer
+	w := ar.NewWriter(&buf, variant)
+	require.NoError(t, w.WriteHeader(&ar.Header{Name: "a.txt", Mode: 0644, Size: 6}))
+	_, err := w.Write([]byte("hello\n"))
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+	path := filepath.Join(dir, "lib.a")
+	require.NoError(t, os.WriteFile(path, buf.Bytes(), 0640))
+	return path
+}
+
+func TestRanlib(t *testing.T) {
+	for _, tc := range []struct {
+		Description string
+		Variant     ar.Variant
+		Symbols     bool
+	}{
+		// GNU-variant archives don't have symbol tables if none of their members define symbols.
+		{"GNU format", ar.GNU, false},
+		{"BSD format", ar.BSD, true},
+	} {
+		t.Run(tc.Description, func(t *testing.T) {
+			path := writeArchive(t, t.TempDir(), tc.Variant)
+			require.NoError(t, run([]string{path}))
+
+			fi, err := os.Stat(path)
+			require.NoError(t, err)
+			assert.Equal(t, os.FileMode(0640), fi.Mode().Perm())
+			f, err := os.Open(path)
+			require.NoError(t, err)
+			defer f.Close()
+			a, err := ar.NewArchive(f, fi.Size())
+			require.NoError(t, err)
+			assert.Equal(t, tc.Variant, a.Variant())
+			require.Len(t, a.Files, 1)
+			assert.Equal(t, "a.txt", a.Files[0].Name)
+			assert.Equal(t, tc.Symbols, a.Symbols() != nil)
+			assert.Empty(t, a.Symbols())
+		})
+	}
+}
+
+func TestRanlibErrors(t *testing.T) {
+	dir := t.TempDir()
+	assert.Error(t, run(nil))
+	assert.Error(t, run([]string{"-X", writeArchive(t, dir, ar.GNU)}))
+	assert.Error(t, run([]string{filepath.Join(dir, "missing.a")}))
+
+	// Files that aren't archives must be left untouched.
+	path := filepath.Join(dir, "not_an_archive.a")
+	require.NoError(t, os.WriteFile(path, []byte("not an archive\n"), 0644))
+	assert.ErrorIs(t, run([]string{path}), ar.ErrInvalidGlobalHeader)
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "not an archive\n", string(data))
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Len(t, entries, 2)
+}
@@ -0,0 +1,245 @@
+package ar
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// Windows import libraries and other archives produced by Microsoft's tools (e.g. lib.exe) use a
+// variant of the GNU format that contains two symbol tables, both named "/", which are known as the
+// first and second linker members. The first linker member has the same format as a GNU symbol
+// table, so Reader and Archive return it from their Symbols methods; the second contains the same
+// symbols sorted by name, and is returned from their COFFLinkerMember methods. Members' long file
+// names are stored in a "//" string table, as in the GNU variant, but are terminated with NUL bytes
+// rather than "/\n".
+
+// COFFLinkerMember is the second linker member of an archive produced by Microsoft's tools.
+type COFFLinkerMember struct {
+	// Offsets are the byte offsets of the headers of the archive's members, relative to the start of
+	// the archive file, in the order in which the members appear in the archive.
+	Offsets []int64
+
+	// Symbols are the entries in the linker member, sorted by name.
+	Symbols []Symbol
+}
+
+// parseCOFFLinkerMember decodes the data section of the second linker member. The data section
+// begins with a little-endian 32-bit integer containing the number of members in the archive,
+// followed by an array of that many little-endian 32-bit integers containing the offsets of the
+// members' headers, followed by a little-endian 32-bit integer containing the number of symbols,
+// followed by an array of that many little-endian 16-bit integers containing the one-based indices
+// into the offset array of the members that define each symbol, followed by the symbols' names, each
+// terminated by a NUL byte.
+func parseCOFFLinkerMember(data []byte) (*COFFLinkerMember, error) {
+	s := slicer(data)
+	if len(s) < 4 {
+		return nil, errors.New("truncated member count")
+	}
+	members := binary.LittleEndian.Uint32(s.next(4))
+	if uint64(members) > uint64(len(s)/4) {
+		return nil, errors.New("truncated offset array")
+	}
+	lm := &COFFLinkerMember{Offsets: make([]int64, members)}
+	for i := range lm.Offsets {
+		lm.Offsets[i] = int64(binary.LittleEndian.Uint32(s.next(4)))
+	}
+	if len(s) < 4 {
+		return nil, errors.New("truncated symbol count")
+	}
+	count := binary.LittleEndian.Uint32(s.next(4))
+	if uint64(count) > uint64(len(s)/2) {
+		return nil, errors.New("truncated index array")
+	}
+	lm.Symbols = make([]Symbol, count)
+	for i := range lm.Symbols {
+		index := int(binary.LittleEndian.Uint16(s.next(2)))
+		if index < 1 || index > len(lm.Offsets) {
+			return nil, fmt.Errorf("invalid member index %d", index)
+		}
+		lm.Symbols[i].Offset = lm.Offsets[index-1]
+	}
+	for i := range lm.Symbols {
+		end := bytes.IndexByte(s, 0)
+		if end == -1 {
+			return nil, errors.New("truncated symbol name list")
+		}
+		lm.Symbols[i].Name = string(s.next(end + 1)[:end])
+	}
+	return lm, nil
+}
+
+// encodeCOFFLinkerMember encodes the data section of the second linker member, in the format
+// described by parseCOFFLinkerMember. The offset of each of lm's symbols must appear in lm.Offsets.
+func encodeCOFFLinkerMember(lm *COFFLinkerMember) []byte {
+	indices := make(map[int64]uint16, len(lm.Offsets))
+	data := binary.LittleEndian.AppendUint32(nil, uint32(len(lm.Offsets)))
+	for i, offset := range lm.Offsets {
+		indices[offset] = uint16(i + 1)
+		data = binary.LittleEndian.AppendUint32(data, uint32(offset))
+	}
+	data = binary.LittleEndian.AppendUint32(data, uint32(len(lm.Symbols)))
+	for _, symbol := range lm.Symbols {
+		data = binary.LittleEndian.AppendUint16(data, indices[symbol.Offset])
+	}
+	for _, symbol := range lm.Symbols {
+		data = append(data, symbol.Name...)
+		data = append(data, 0)
+	}
+	return data
+}
+
+// ImportType is the type of the symbol imported by a short import object.
+type ImportType int
+
+const (
+	// ImportCode indicates that the imported symbol is a function.
+	ImportCode ImportType = iota
+
+	// ImportData indicates that the imported symbol is a variable.
+	ImportData
+
+	// ImportConst indicates that the imported symbol is a constant.
+	ImportConst
+)
+
+// ImportNameType determines how the name by which a symbol is imported from a DLL is derived from a
+// short import object.
+type ImportNameType int
+
+const (
+	// ImportOrdinal indicates that the symbol is imported by ordinal rather than by name.
+	ImportOrdinal ImportNameType = iota
+
+	// ImportName indicates that the symbol is imported by its own name.
+	ImportName
+
+	// ImportNameNoPrefix indicates that the symbol is imported by its own name, with any leading "?",
+	// "@" or "_" removed.
+	ImportNameNoPrefix
+
+	// ImportNameUndecorate indicates that the symbol is imported by its own name, with any leading
+	// "?", "@" or "_" removed and truncated at the first "@".
+	ImportNameUndecorate
+
+	// ImportNameExportAs indicates that the symbol is imported by the name given in the short import
+	// object's ExportName field.
+	ImportNameExportAs
+)
+
+// importObjectHeaderSize is the size of a short import object's header.
+const importObjectHeaderSize = 20
+
+// ImportObject is a short import object, which Windows import libraries contain instead of a COFF
+// object file for each symbol exported by the DLL they describe.
+type ImportObject struct {
+	// Machine is the machine type that the import library targets (e.g. 0x8664 for x86-64).
+	Machine uint16
+
+	// TimeDateStamp is the time at which the import object was created, in seconds since the Unix
+	// epoch.
+	TimeDateStamp uint32
+
+	// Type is the type of the imported symbol.
+	Type ImportType
+
+	// NameType determines the name by which the symbol is imported from the DLL.
+	NameType ImportNameType
+
+	// Ordinal is the ordinal by which the symbol is imported, if NameType is ImportOrdinal.
+	Ordinal uint16
+
+	// Hint is the index into the DLL's export name table at which the symbol's name is likely to be
+	// found, if NameType isn't ImportOrdinal.
+	Hint uint16
+
+	// Symbol is the name of the imported symbol.
+	Symbol string
+
+	// DLL is the name of the DLL from which the symbol is imported.
+	DLL string
+
+	// ExportName is the name by which the symbol is imported, if NameType is ImportNameExportAs.
+	ExportName string
+}
+
+// IsImportObject returns whether data, the data section of an archive member, is a short import
+// object rather than a COFF object file.
+func IsImportObject(data []byte) bool {
+	return len(data) >= 4 && binary.LittleEndian.Uint16(data) == 0 && binary.LittleEndian.Uint16(data[2:]) == 0xffff
+}
+
+// ParseImportObject decodes a short import object from data, the data section of an archive member.
+func ParseImportObject(data []byte) (*ImportObject, error) {
+	if !IsImportObject(data) {
+		return nil, errors.New("ar: not a short import object")
+	}
+	if len(data) < importObjectHeaderSize {
+		return nil, errors.New("ar: truncated short import object header")
+	}
+	s := slicer(data[4:importObjectHeaderSize])
+	obj := &ImportObject{}
+	if version := binary.LittleEndian.Uint16(s.next(2)); version != 0 {
+		return nil, fmt.Errorf("ar: unsupported short import object version %d", version)
+	}
+	obj.Machine = binary.LittleEndian.Uint16(s.next(2))
+	obj.TimeDateStamp = binary.LittleEndian.Uint32(s.next(4))
+	size := binary.LittleEndian.Uint32(s.next(4))
+	ordinalOrHint := binary.LittleEndian.Uint16(s.next(2))
+	flags := binary.LittleEndian.Uint16(s.next(2))
+	obj.Type = ImportType(flags & 0x3)
+	obj.NameType = ImportNameType((flags >> 2) & 0x7)
+	if obj.NameType == ImportOrdinal {
+		obj.Ordinal = ordinalOrHint
+	} else {
+		obj.Hint = ordinalOrHint
+	}
+	if uint64(size) > uint64(len(data)-importObjectHeaderSize) {
+		return nil, errors.New("ar: truncated short import object")
+	}
+	// The header is followed by the NUL-terminated symbol name and DLL name, and (if NameType is
+	// ImportNameExportAs) the NUL-terminated export name.
+	names := bytes.Split(data[importObjectHeaderSize:importObjectHeaderSize+size], []byte{0})
+	want := 3
+	if obj.NameType == ImportNameExportAs {
+		want = 4
+	}
+	if len(names) < want {
+		return nil, errors.New("ar: truncated short import object name list")
+	}
+	obj.Symbol, obj.DLL = string(names[0]), string(names[1])
+	if obj.NameType == ImportNameExportAs {
+		obj.ExportName = string(names[2])
+	}
+	return obj, nil
+}
+
+// symbols returns the names of the symbols that the short import object defines: its import address
+// table entry, "__imp_" followed by its symbol name, and (unless it imports a variable) a thunk with
+// its symbol name.
+func (obj *ImportObject) symbols() []string {
+	if obj.Type == ImportData {
+		return []string{"__imp_" + obj.Symbol}
+	}
+	return []string{"__imp_" + obj.Symbol, obj.Symbol}
+}
+
+// encode encodes obj as a short import object, in the format decoded by ParseImportObject.
+func (obj *ImportObject) encode() []byte {
+	names := obj.Symbol + "\x00" + obj.DLL + "\x00"
+	if obj.NameType == ImportNameExportAs {
+		names += obj.ExportName + "\x00"
+	}
+	ordinalOrHint := obj.Hint
+	if obj.NameType == ImportOrdinal {
+		ordinalOrHint = obj.Ordinal
+	}
+	data := []byte{0, 0, 0xff, 0xff, 0, 0}
+	data = binary.LittleEndian.AppendUint16(data, obj.Machine)
+	data = binary.LittleEndian.AppendUint32(data, obj.TimeDateStamp)
+	data = binary.LittleEndian.AppendUint32(data, uint32(len(names)))
+	data = binary.LittleEndian.AppendUint16(data, ordinalOrHint)
+	data = binary.LittleEndian.AppendUint16(data, uint16(obj.NameType)<<2|uint16(obj.Type))
+	return append(data, names...)
+}
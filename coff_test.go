@@ -0,0 +1,152 @@
+package ar
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"os"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// coffImportObjects are the short import objects in test_data/coff_import.lib, which was generated
+// from the following module-definition file and rewritten in the format produced by Microsoft's
+// lib.exe (with two linker members and NUL-terminated long file names):
+//
+//	LIBRARY a_long_library_name.dll
+//	EXPORTS
+//	    HelloWorld
+//	    Goodbye @5
+//	    SecretFunction @7 NONAME
+//	    GlobalCounter DATA
+var coffImportObjects = []*ImportObject{
+	{Machine: 0x8664, Type: ImportCode, NameType: ImportName, Symbol: "HelloWorld", DLL: "a_long_library_name.dll"},
+	{Machine: 0x8664, Type: ImportCode, NameType: ImportName, Hint: 5, Symbol: "Goodbye", DLL: "a_long_library_name.dll"},
+	{Machine: 0x8664, Type: ImportCode, NameType: ImportOrdinal, Ordinal: 7, Symbol: "SecretFunction", DLL: "a_long_library_name.dll"},
+	{Machine: 0x8664, Type: ImportData, NameType: ImportName, Symbol: "GlobalCounter", DLL: "a_long_library_name.dll"},
+}
+
+func TestReadCOFFImportLibrary(t *testing.T) {
+	f, err := os.Open("./test_data/coff_import.lib")
+	require.NoError(t, err)
+	defer f.Close()
+	reader, err := NewReaderWithOptions(f, ReaderOptions{Strict: true})
+	require.NoError(t, err)
+	var offsets []int64
+	var objects []*ImportObject
+	for {
+		hdr, err := reader.Next()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		assert.Equal(t, "a_long_library_name.dll", hdr.Name)
+		offsets = append(offsets, reader.HeaderOffset())
+		data, err := io.ReadAll(reader)
+		require.NoError(t, err)
+		if IsImportObject(data) {
+			obj, err := ParseImportObject(data)
+			require.NoError(t, err)
+			objects = append(objects, obj)
+		}
+	}
+	require.Len(t, offsets, 7)
+	assert.Equal(t, coffImportObjects, objects)
+
+	// The first linker member lists the symbols in the order of the members that define them...
+	symbols := reader.Symbols()
+	require.Len(t, symbols, 10)
+	assert.Equal(t, Symbol{Name: "__IMPORT_DESCRIPTOR_a_long_library_name", Offset: offsets[0]}, symbols[0])
+	assert.Equal(t, Symbol{Name: "__imp_GlobalCounter", Offset: offsets[6]}, symbols[9])
+	assert.Contains(t, reader.SymbolTable(), "Goodbye")
+
+	// ...and the second lists the same symbols, sorted by name.
+	lm := reader.COFFLinkerMember()
+	require.NotNil(t, lm)
+	assert.Equal(t, offsets, lm.Offsets)
+	sorted := append([]Symbol{}, symbols...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Name < sorted[j].Name })
+	assert.Equal(t, sorted, lm.Symbols)
+}
+
+func TestArchiveCOFFImportLibrary(t *testing.T) {
+	archive := openArchive(t, "./test_data/coff_import.lib")
+	assert.Equal(t, GNU, archive.Variant())
+	require.Len(t, archive.Files, 7)
+	goodbye := archive.SymbolTable()["__imp_Goodbye"]
+	require.NotNil(t, goodbye)
+	r, err := goodbye.Open()
+	require.NoError(t, err)
+	data, err := io.ReadAll(r)
+	require.NoError(t, err)
+	obj, err := ParseImportObject(data)
+	require.NoError(t, err)
+	assert.Equal(t, coffImportObjects[1], obj)
+
+	lm := archive.COFFLinkerMember()
+	require.NotNil(t, lm)
+	require.Len(t, lm.Symbols, 10)
+	assert.Equal(t, Symbol{Name: "Goodbye", Offset: goodbye.HeaderOffset()}, lm.Symbols[0])
+
+	assert.Nil(t, openArchive(t, "./test_data/symbols_gnu.a").COFFLinkerMember())
+}
+
+func TestParseCOFFLinkerMemberErrors(t *testing.T) {
+	le := func(words ...interface{}) []byte {
+		var buf bytes.Buffer
+		for _, w := range words {
+			require.NoError(t, binary.Write(&buf, binary.LittleEndian, w))
+		}
+		return buf.Bytes()
+	}
+	for _, tc := range []struct {
+		Description string
+		Data        []byte
+	}{
+		{"Empty", nil},
+		{"Truncated offset array", le(uint32(2), uint32(8))},
+		{"Truncated symbol count", le(uint32(1), uint32(8))},
+		{"Truncated index array", le(uint32(1), uint32(8), uint32(2), uint16(1))},
+		{"Invalid member index", append(le(uint32(1), uint32(8), uint32(1), uint16(2)), "a\x00"...)},
+		{"Truncated symbol name list", append(le(uint32(1), uint32(8), uint32(1), uint16(1)), "a"...)},
+	} {
+		t.Run(tc.Description, func(t *testing.T) {
+			_, err := parseCOFFLinkerMember(tc.Data)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestParseImportObjectErrors(t *testing.T) {
+	header := func(version uint16, size uint32, flags uint16) []byte {
+		b := []byte{0, 0, 0xff, 0xff}
+		b = binary.LittleEndian.AppendUint16(b, version)
+		b = binary.LittleEndian.AppendUint16(b, 0x8664)
+		b = binary.LittleEndian.AppendUint32(b, 0)
+		b = binary.LittleEndian.AppendUint32(b, size)
+		b = binary.LittleEndian.AppendUint16(b, 0)
+		return binary.LittleEndian.AppendUint16(b, flags)
+	}
+	for _, tc := range []struct {
+		Description string
+		Data        []byte
+	}{
+		{"COFF object file", []byte{0x64, 0x86, 2, 0}},
+		{"Truncated header", header(0, 0, 0)[:12]},
+		{"Unsupported version", append(header(1, 6, 4), "a\x00b.dll\x00"...)},
+		{"Truncated names", append(header(0, 8, 4), "a\x00b.dll"...)},
+		{"Missing DLL name", append(header(0, 2, 4), "a\x00"...)},
+		{"Missing export name", append(header(0, 8, 16), "a\x00b.dll\x00"...)},
+	} {
+		t.Run(tc.Description, func(t *testing.T) {
+			_, err := ParseImportObject(tc.Data)
+			assert.Error(t, err)
+		})
+	}
+	obj, err := ParseImportObject(append(header(0, 10, 16), "a\x00b.dll\x00c\x00"...))
+	require.NoError(t, err)
+	assert.Equal(t, &ImportObject{Machine: 0x8664, NameType: ImportNameExportAs, Symbol: "a", DLL: "b.dll", ExportName: "c"}, obj)
+}
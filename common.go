@@ -7,6 +7,7 @@ import (
 const (
 	HEADER_BYTE_SIZE = 60
 	GLOBAL_HEADER = "!<arch>\n"
+	THIN_GLOBAL_HEADER = "!<thin>\n"
 )
 
 type Variant int
@@ -0,0 +1,47 @@
+package ar
+
+import (
+	"errors"
+	"io"
+)
+
+// Convert writes to w a copy of the archive a using the given variant of the ar file format. Long
+// file names are moved between the GNU variant's string table and the BSD variant's prepended file
+// names, and the data sections of members are copied directly from the original archive file.
+//
+// If a has a symbol table, it is translated into the given variant's symbol table format, with the
+// symbols' offsets adjusted to those of the members in the converted archive. This preserves the
+// symbol table even if the members aren't object files that the Writer can parse. If opts.SymbolIndex
+// is set, a new symbol table is generated from the members' data sections instead.
+//
+// Thin archives must use the GNU variant, so they can't be converted to the BSD variant.
+func Convert(w io.Writer, a *Archive, variant Variant, opts WriterOptions) error {
+	if a.Thin() && variant != GNU {
+		return errors.New("ar: thin archives can't be converted to the BSD variant")
+	}
+	opts.Thin = a.Thin()
+	members, err := archiveMembers(a, opts.SymbolIndex, nil, nil)
+	if err != nil {
+		return err
+	}
+	if symbols := a.Symbols(); symbols != nil && !opts.SymbolIndex {
+		opts.SymbolIndex = true
+		byOffset := map[int64]int{}
+		for i, f := range a.Files {
+			byOffset[f.headerOffset] = i
+			members[i].symbols = []string{}
+		}
+		for _, sym := range symbols {
+			i := byOffset[sym.Offset]
+			members[i].symbols = append(members[i].symbols, sym.Name)
+		}
+	}
+	out := newWriter(w, variant, opts)
+	// Microsoft's format is an extension of the GNU variant, so it can only be preserved if the
+	// archive remains a GNU-variant archive.
+	out.coff = variant == GNU && a.COFFLinkerMember() != nil
+	if err := out.writeArchive(members, nil); err != nil {
+		return err
+	}
+	return out.Close()
+}
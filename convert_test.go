@@ -0,0 +1,102 @@
+package ar
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// symbolMembers returns the names of the members that define each symbol in the archive's symbol
+// table, in the order in which the symbols appear in the table.
+func symbolMembers(a *Archive) [][2]string {
+	byOffset := map[int64]string{}
+	for _, f := range a.Files {
+		byOffset[f.HeaderOffset()] = f.Name
+	}
+	var symbols [][2]string
+	for _, sym := range a.Symbols() {
+		symbols = append(symbols, [2]string{sym.Name, byOffset[sym.Offset]})
+	}
+	return symbols
+}
+
+func TestConvert(t *testing.T) {
+	for _, tc := range []struct {
+		Description string
+		From, To    string
+		Variant     Variant
+	}{
+		{"GNU to BSD", "long_filenames_gnu.a", "long_filenames_bsd.a", BSD},
+		{"BSD to GNU", "long_filenames_bsd.a", "long_filenames_gnu.a", GNU},
+		{"GNU to BSD with symbol table", "symbols_gnu.a", "symbols_bsd.a", BSD},
+		{"BSD to GNU with symbol table", "symbols_bsd.a", "symbols_gnu.a", GNU},
+		{"GNU to GNU", "symbols_gnu.a", "symbols_gnu.a", GNU},
+	} {
+		t.Run(tc.Description, func(t *testing.T) {
+			var buf bytes.Buffer
+			require.NoError(t, Convert(&buf, openArchive(t, "./test_data/"+tc.From), tc.Variant, WriterOptions{}))
+			path := filepath.Join(t.TempDir(), "converted.a")
+			require.NoError(t, os.WriteFile(path, buf.Bytes(), 0644))
+
+			expectedHdrs, expectedData := readMembers(t, "./test_data/"+tc.To)
+			actualHdrs, actualData := readMembers(t, path)
+			assert.Equal(t, expectedHdrs, actualHdrs)
+			assert.Equal(t, expectedData, actualData)
+			expected := openArchive(t, "./test_data/"+tc.To)
+			actual := openArchive(t, path)
+			assert.Equal(t, tc.Variant, actual.Variant())
+			assert.ElementsMatch(t, symbolMembers(expected), symbolMembers(actual))
+		})
+	}
+}
+
+func TestConvertPreservesSymbolTable(t *testing.T) {
+	// The members aren't object files, so the symbol table can only have been translated from the
+	// original archive's.
+	var buf bytes.Buffer
+	writer := NewWriter(&buf, GNU)
+	require.NoError(t, writer.WriteSymbolTable([]Symbol{{Name: "foo", Offset: 180}, {Name: "bar", Offset: 180}, {Name: "baz", Offset: 242}}))
+	require.NoError(t, writer.WriteStringTable([]string{"a_long_member_name.txt"}))
+	for _, name := range []string{"a_long_member_name.txt", "b.txt"} {
+		require.NoError(t, writer.WriteHeader(&Header{Name: name, Mode: 0644, Size: 2}))
+		_, err := writer.Write([]byte("hi"))
+		require.NoError(t, err)
+	}
+	require.NoError(t, writer.Close())
+	original, err := NewArchive(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	require.NoError(t, err)
+	require.Equal(t, [][2]string{{"foo", "a_long_member_name.txt"}, {"bar", "a_long_member_name.txt"}, {"baz", "b.txt"}}, symbolMembers(original))
+
+	for _, variant := range []Variant{BSD, GNU} {
+		var converted bytes.Buffer
+		require.NoError(t, Convert(&converted, original, variant, WriterOptions{}))
+		archive, err := NewArchive(bytes.NewReader(converted.Bytes()), int64(converted.Len()))
+		require.NoError(t, err)
+		assert.ElementsMatch(t, symbolMembers(original), symbolMembers(archive))
+		original = archive
+	}
+
+	// Regenerating the symbol table discards the translated symbols.
+	var converted bytes.Buffer
+	require.NoError(t, Convert(&converted, original, BSD, WriterOptions{SymbolIndex: true}))
+	archive, err := NewArchive(bytes.NewReader(converted.Bytes()), int64(converted.Len()))
+	require.NoError(t, err)
+	assert.NotNil(t, archive.Symbols())
+	assert.Empty(t, archive.Symbols())
+}
+
+func TestConvertThin(t *testing.T) {
+	var buf bytes.Buffer
+	assert.Error(t, Convert(&buf, openArchive(t, "./test_data/thin.a"), BSD, WriterOptions{}))
+
+	original := openArchive(t, "./test_data/thin.a")
+	require.NoError(t, Convert(&buf, original, GNU, WriterOptions{}))
+	archive, err := NewArchive(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	require.NoError(t, err)
+	assert.True(t, archive.Thin())
+	assert.Equal(t, symbolMembers(original), symbolMembers(archive))
+}
@@ -0,0 +1,97 @@
+// Package deb reads and writes Debian binary packages (.deb files).
+//
+// A .deb file is an ar archive containing three members, in order:
+//
+//	debian-binary         the format version, "2.0\n"
+//	control.tar[.ext]     a tarball containing the package's control file and maintainer scripts
+//	data.tar[.ext]        a tarball containing the files installed by the package
+//
+// The tarballs may be compressed, in which case their names have an extension identifying the
+// compression format (e.g. "data.tar.xz"). Members whose names begin with "_" are reserved for local
+// use, and are ignored.
+package deb
+
+import (
+	"compress/bzip2"
+	"compress/gzip"
+	"errors"
+	"io"
+	"sync"
+)
+
+const (
+	// debianBinary is the name of the member containing the format version.
+	debianBinary = "debian-binary"
+
+	// controlTar and dataTar are the names of the tarball members, without compression extensions.
+	controlTar = "control.tar"
+	dataTar    = "data.tar"
+
+	// formatVersion is the format version written by Build.
+	formatVersion = "2.0\n"
+)
+
+var (
+	// ErrInvalidPackage indicates that a .deb file's members are missing, misnamed or out of order,
+	// or that its format version is unsupported.
+	ErrInvalidPackage = errors.New("deb: invalid package")
+
+	// ErrUnsupportedCompression indicates that a tarball in a .deb file is compressed in a format for
+	// which no Decompressor is registered.
+	ErrUnsupportedCompression = errors.New("deb: unsupported compression")
+)
+
+// A Decompressor returns a reader that decompresses data read from r. The Reader calls Close on the
+// returned io.ReadCloser once the tarball it decompresses is no longer needed.
+type Decompressor func(r io.Reader) (io.ReadCloser, error)
+
+// decompressors maps the extensions of compressed tarballs' member names (e.g. ".gz") to the
+// registered Decompressors. The standard library provides decompressors for gzip and bzip2; callers
+// must register decompressors for other formats (e.g. ".xz" and ".zst") themselves.
+var decompressors sync.Map
+
+func init() {
+	decompressors.Store("", Decompressor(func(r io.Reader) (io.ReadCloser, error) {
+		return io.NopCloser(r), nil
+	}))
+	decompressors.Store(".gz", Decompressor(func(r io.Reader) (io.ReadCloser, error) {
+		return gzip.NewReader(r)
+	}))
+	decompressors.Store(".bz2", Decompressor(func(r io.Reader) (io.ReadCloser, error) {
+		return io.NopCloser(bzip2.NewReader(r)), nil
+	}))
+}
+
+// RegisterDecompressor registers a Decompressor for tarballs whose member names have the given
+// extension (including the leading ".", e.g. ".xz"). It panics if a Decompressor is already
+// registered for the extension.
+func RegisterDecompressor(ext string, d Decompressor) {
+	if _, dup := decompressors.LoadOrStore(ext, d); dup {
+		panic("deb: decompressor already registered for " + ext)
+	}
+}
+
+// A Compressor compresses the tarballs written by Build.
+type Compressor struct {
+	// Extension is the extension added to the names of tarballs compressed by the Compressor,
+	// including the leading "." (e.g. ".gz").
+	Extension string
+
+	// NewWriter returns a writer that compresses data written to it and writes it to w. Build calls
+	// Close on the returned io.WriteCloser once the tarball has been written. If NewWriter is nil,
+	// tarballs are not compressed.
+	NewWriter func(w io.Writer) (io.WriteCloser, error)
+}
+
+var (
+	// Gzip compresses tarballs with gzip.
+	Gzip = &Compressor{
+		Extension: ".gz",
+		NewWriter: func(w io.Writer) (io.WriteCloser, error) {
+			return gzip.NewWriter(w), nil
+		},
+	}
+
+	// Uncompressed leaves tarballs uncompressed.
+	Uncompressed = &Compressor{}
+)
@@ -0,0 +1,195 @@
+package deb
+
+import (
+	"archive/tar"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/please-build/ar"
+)
+
+// maxVersionSize is the maximum size of the debian-binary member that the Reader accepts.
+const maxVersionSize = 64
+
+// Reader reads a .deb file sequentially, validating the names and order of its members as it goes.
+// The control tarball must be read (by calling Control) before the data tarball (by calling Data),
+// although Data may be called without calling Control first.
+//
+// Example:
+//
+//	rd, err := deb.NewReader(f)
+//	if err != nil {
+//		return err
+//	}
+//	defer rd.Close()
+//	data, err := rd.Data()
+//	if err != nil {
+//		return err
+//	}
+//	for {
+//		hdr, err := data.Next()
+//		if err == io.EOF {
+//			break
+//		} else if err != nil {
+//			return err
+//		}
+//		fmt.Println(hdr.Name)
+//	}
+type Reader struct {
+	ar *ar.Reader
+
+	// version is the contents of the debian-binary member, without its trailing newline.
+	version string
+
+	// decompressors are the Decompressors registered with this Reader, which take precedence over
+	// those registered with RegisterDecompressor.
+	decompressors map[string]Decompressor
+
+	// next is the name (without compression extension) of the next tarball member to be read, or ""
+	// if both tarballs have been read.
+	next string
+
+	// decompressor is the decompressor for the tarball currently being read, or nil if there is
+	// none.
+	decompressor io.Closer
+}
+
+// NewReader creates a new Reader reading a .deb file from r. It reads the debian-binary member, and
+// returns an error wrapping ErrInvalidPackage if it is missing or specifies an unsupported format
+// version.
+func NewReader(r io.Reader) (*Reader, error) {
+	arReader, err := ar.NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+	rd := &Reader{ar: arReader, next: controlTar}
+	hdr, err := rd.ar.Next()
+	if err == io.EOF {
+		return nil, fmt.Errorf("%w: missing %s member", ErrInvalidPackage, debianBinary)
+	} else if err != nil {
+		return nil, err
+	}
+	if hdr.Name != debianBinary {
+		return nil, fmt.Errorf("%w: first member is '%s', not %s", ErrInvalidPackage, hdr.Name, debianBinary)
+	}
+	if hdr.Size > maxVersionSize {
+		return nil, fmt.Errorf("%w: %s member is too large", ErrInvalidPackage, debianBinary)
+	}
+	version, err := io.ReadAll(rd.ar)
+	if err != nil {
+		return nil, err
+	}
+	rd.version = strings.TrimSuffix(string(version), "\n")
+	// Readers must accept any minor version of the format, but not any other major version.
+	if !strings.HasPrefix(rd.version, "2.") {
+		return nil, fmt.Errorf("%w: unsupported format version '%s'", ErrInvalidPackage, rd.version)
+	}
+	return rd, nil
+}
+
+// Version returns the format version given in the package's debian-binary member (e.g. "2.0").
+func (rd *Reader) Version() string {
+	return rd.version
+}
+
+// RegisterDecompressor registers a Decompressor for tarballs whose member names have the given
+// extension (including the leading ".", e.g. ".xz"), for use by this Reader only. It overrides any
+// Decompressor registered for the extension with the package-level RegisterDecompressor.
+func (rd *Reader) RegisterDecompressor(ext string, d Decompressor) {
+	if rd.decompressors == nil {
+		rd.decompressors = map[string]Decompressor{}
+	}
+	rd.decompressors[ext] = d
+}
+
+// Control advances to the control tarball, returning a tar.Reader that reads its decompressed
+// contents. It returns an error wrapping ErrInvalidPackage if the control tarball is missing, and one
+// wrapping ErrUnsupportedCompression if no Decompressor is registered for its compression format.
+func (rd *Reader) Control() (*tar.Reader, error) {
+	if rd.next != controlTar {
+		return nil, fmt.Errorf("deb: %s has already been read", controlTar)
+	}
+	return rd.tarball(controlTar)
+}
+
+// Data advances to the data tarball, skipping the control tarball if Control hasn't been called,
+// and returns a tar.Reader that reads its decompressed contents. It returns an error wrapping
+// ErrInvalidPackage if either tarball is missing, and one wrapping ErrUnsupportedCompression if no
+// Decompressor is registered for the data tarball's compression format.
+func (rd *Reader) Data() (*tar.Reader, error) {
+	if rd.next == controlTar {
+		if _, err := rd.member(controlTar); err != nil {
+			return nil, err
+		}
+		rd.next = dataTar
+	}
+	if rd.next != dataTar {
+		return nil, fmt.Errorf("deb: %s has already been read", dataTar)
+	}
+	return rd.tarball(dataTar)
+}
+
+// Close closes the decompressor for the tarball currently being read, if any. It does not close the
+// underlying io.Reader.
+func (rd *Reader) Close() error {
+	if rd.decompressor == nil {
+		return nil
+	}
+	err := rd.decompressor.Close()
+	rd.decompressor = nil
+	return err
+}
+
+// tarball advances to the named tarball and returns a tar.Reader for it.
+func (rd *Reader) tarball(name string) (*tar.Reader, error) {
+	hdr, err := rd.member(name)
+	if err != nil {
+		return nil, err
+	}
+	if name == controlTar {
+		rd.next = dataTar
+	} else {
+		rd.next = ""
+	}
+	ext := strings.TrimPrefix(hdr.Name, name)
+	d := rd.decompressors[ext]
+	if d == nil {
+		if v, ok := decompressors.Load(ext); ok {
+			d = v.(Decompressor)
+		}
+	}
+	if d == nil {
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedCompression, hdr.Name)
+	}
+	r, err := d(rd.ar)
+	if err != nil {
+		return nil, fmt.Errorf("deb: %s: %w", hdr.Name, err)
+	}
+	rd.decompressor = r
+	return tar.NewReader(r), nil
+}
+
+// member advances to the member containing the named tarball, skipping any members whose names
+// begin with "_".
+func (rd *Reader) member(name string) (*ar.Header, error) {
+	if err := rd.Close(); err != nil {
+		return nil, err
+	}
+	for {
+		hdr, err := rd.ar.Next()
+		if err == io.EOF {
+			return nil, fmt.Errorf("%w: missing %s member", ErrInvalidPackage, name)
+		} else if err != nil {
+			return nil, err
+		}
+		if strings.HasPrefix(hdr.Name, "_") {
+			continue
+		}
+		ext := strings.TrimPrefix(hdr.Name, name)
+		if ext == hdr.Name || (ext != "" && !strings.HasPrefix(ext, ".")) {
+			return nil, fmt.Errorf("%w: expected %s member, found '%s'", ErrInvalidPackage, name, hdr.Name)
+		}
+		return hdr, nil
+	}
+}
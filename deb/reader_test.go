@@ -0,0 +1,215 @@
+package deb
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"os"
+	"testing"
+
+	"github.com/please-build/ar"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// tarEntry is an entry in a tarball.
+type tarEntry struct {
+	Name, Data string
+}
+
+// readTar returns the entries in a tarball.
+func readTar(t *testing.T, tr *tar.Reader) []tarEntry {
+	var entries []tarEntry
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return entries
+		}
+		require.NoError(t, err)
+		data, err := io.ReadAll(tr)
+		require.NoError(t, err)
+		entries = append(entries, tarEntry{hdr.Name, entryData(hdr, data)})
+	}
+}
+
+// entryData returns the data of a tarball entry as a string, or the target of a symbolic link.
+func entryData(hdr *tar.Header, data []byte) string {
+	if hdr.Typeflag == tar.TypeSymlink {
+		return "-> " + hdr.Linkname
+	}
+	return string(data)
+}
+
+// testDeb returns a .deb file containing members with the given names and contents.
+func testDeb(t *testing.T, members ...string) []byte {
+	var buf bytes.Buffer
+	writer := ar.NewWriter(&buf, ar.BSD)
+	for i := 0; i < len(members); i += 2 {
+		require.NoError(t, writer.WriteHeader(&ar.Header{Name: members[i], Mode: 0644, Size: int64(len(members[i+1]))}))
+		_, err := writer.Write([]byte(members[i+1]))
+		require.NoError(t, err)
+	}
+	require.NoError(t, writer.Close())
+	return buf.Bytes()
+}
+
+// testTar returns a tarball containing a single file with the given name and contents.
+func testTar(t *testing.T, name, data string) string {
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	require.NoError(t, tw.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: int64(len(data))}))
+	_, err := tw.Write([]byte(data))
+	require.NoError(t, err)
+	require.NoError(t, tw.Close())
+	return buf.String()
+}
+
+var helloControl = []tarEntry{
+	{"./", ""},
+	{"./control", "Package: hello\nVersion: 1.0\nArchitecture: all\nMaintainer: Please <please@example.com>\nDescription: A test package\n"},
+	{"./postinst", "#!/bin/sh\necho installed\n"},
+}
+
+var helloData = []tarEntry{
+	{"./", ""},
+	{"./usr/", ""},
+	{"./usr/share/", ""},
+	{"./usr/share/hello/", ""},
+	{"./usr/share/hello/hello.txt", "hello\n"},
+	{"./usr/share/hello/link.txt", "-> hello.txt"},
+}
+
+func TestReader(t *testing.T) {
+	f, err := os.Open("./test_data/hello_gzip.deb")
+	require.NoError(t, err)
+	defer f.Close()
+	rd, err := NewReader(f)
+	require.NoError(t, err)
+	defer rd.Close()
+	assert.Equal(t, "2.0", rd.Version())
+
+	control, err := rd.Control()
+	require.NoError(t, err)
+	assert.Equal(t, helloControl, readTar(t, control))
+	data, err := rd.Data()
+	require.NoError(t, err)
+	assert.Equal(t, helloData, readTar(t, data))
+
+	_, err = rd.Control()
+	assert.Error(t, err)
+	_, err = rd.Data()
+	assert.Error(t, err)
+}
+
+func TestReaderSkipsControl(t *testing.T) {
+	f, err := os.Open("./test_data/hello_gzip.deb")
+	require.NoError(t, err)
+	defer f.Close()
+	rd, err := NewReader(f)
+	require.NoError(t, err)
+	defer rd.Close()
+	data, err := rd.Data()
+	require.NoError(t, err)
+	assert.Equal(t, helloData, readTar(t, data))
+}
+
+func TestReaderUnsupportedCompression(t *testing.T) {
+	f, err := os.Open("./test_data/hello_xz.deb")
+	require.NoError(t, err)
+	defer f.Close()
+	rd, err := NewReader(f)
+	require.NoError(t, err)
+	_, err = rd.Control()
+	assert.ErrorIs(t, err, ErrUnsupportedCompression)
+}
+
+func TestReaderRegisterDecompressor(t *testing.T) {
+	// The ".rev" compression format reverses the tarball.
+	reverse := func(r io.Reader) (io.ReadCloser, error) {
+		data, err := io.ReadAll(r)
+		if err != nil {
+			return nil, err
+		}
+		for i, j := 0, len(data)-1; i < j; i, j = i+1, j-1 {
+			data[i], data[j] = data[j], data[i]
+		}
+		return io.NopCloser(bytes.NewReader(data)), nil
+	}
+	reversed := func(s string) string {
+		b := []byte(s)
+		for i, j := 0, len(b)-1; i < j; i, j = i+1, j-1 {
+			b[i], b[j] = b[j], b[i]
+		}
+		return string(b)
+	}
+	deb := testDeb(t,
+		"debian-binary", "2.0\n",
+		"control.tar.rev", reversed(testTar(t, "./control", "Package: rev\n")),
+		"data.tar", testTar(t, "./file", "data"),
+	)
+	rd, err := NewReader(bytes.NewReader(deb))
+	require.NoError(t, err)
+	_, err = rd.Control()
+	assert.ErrorIs(t, err, ErrUnsupportedCompression)
+
+	rd, err = NewReader(bytes.NewReader(deb))
+	require.NoError(t, err)
+	rd.RegisterDecompressor(".rev", reverse)
+	control, err := rd.Control()
+	require.NoError(t, err)
+	assert.Equal(t, []tarEntry{{"./control", "Package: rev\n"}}, readTar(t, control))
+	data, err := rd.Data()
+	require.NoError(t, err)
+	assert.Equal(t, []tarEntry{{"./file", "data"}}, readTar(t, data))
+}
+
+func TestReaderInvalidPackages(t *testing.T) {
+	var gz bytes.Buffer
+	zw := gzip.NewWriter(&gz)
+	_, err := zw.Write([]byte(testTar(t, "./control", "Package: test\n")))
+	require.NoError(t, err)
+	require.NoError(t, zw.Close())
+	controlTarGz := gz.String()
+	dataTar := testTar(t, "./file", "data")
+
+	for _, tc := range []struct {
+		Description string
+		Members     []string
+	}{
+		{"Empty archive", nil},
+		{"Missing debian-binary", []string{"control.tar.gz", controlTarGz, "data.tar", dataTar}},
+		{"Unsupported format version", []string{"debian-binary", "3.0\n", "control.tar.gz", controlTarGz, "data.tar", dataTar}},
+		{"Missing control tarball", []string{"debian-binary", "2.0\n", "data.tar", dataTar}},
+		{"Missing data tarball", []string{"debian-binary", "2.0\n", "control.tar.gz", controlTarGz}},
+		{"Misnamed tarball", []string{"debian-binary", "2.0\n", "control.tarball", controlTarGz, "data.tar", dataTar}},
+		{"Tarballs out of order", []string{"debian-binary", "2.0\n", "data.tar", dataTar, "control.tar.gz", controlTarGz}},
+	} {
+		t.Run(tc.Description, func(t *testing.T) {
+			rd, err := NewReader(bytes.NewReader(testDeb(t, tc.Members...)))
+			if err == nil {
+				_, err = rd.Data()
+			}
+			assert.ErrorIs(t, err, ErrInvalidPackage)
+		})
+	}
+}
+
+func TestReaderIgnoresReservedMembers(t *testing.T) {
+	deb := testDeb(t,
+		"debian-binary", "2.1\n",
+		"_gpgorigin", "signature",
+		"control.tar", testTar(t, "./control", "Package: test\n"),
+		"_extra", "",
+		"data.tar", testTar(t, "./file", "data"),
+	)
+	rd, err := NewReader(bytes.NewReader(deb))
+	require.NoError(t, err)
+	assert.Equal(t, "2.1", rd.Version())
+	control, err := rd.Control()
+	require.NoError(t, err)
+	assert.Equal(t, []tarEntry{{"./control", "Package: test\n"}}, readTar(t, control))
+	data, err := rd.Data()
+	require.NoError(t, err)
+	assert.Equal(t, []tarEntry{{"./file", "data"}}, readTar(t, data))
+}
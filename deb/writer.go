@@ -0,0 +1,177 @@
+package deb
+
+import (
+	"archive/tar"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/please-build/ar"
+)
+
+// BuildOptions specifies optional behaviour for Build.
+type BuildOptions struct {
+	// ControlCompression and DataCompression are the Compressors used to compress the control and
+	// data tarballs. If they are nil, Gzip is used.
+	ControlCompression, DataCompression *Compressor
+
+	// ModTime is the modification time given to the archive members and to every file in the
+	// tarballs, which allows packages to be built reproducibly (like dpkg-deb's use of the
+	// SOURCE_DATE_EPOCH environment variable). If it is zero, the archive members are given the
+	// current time, and the files in the tarballs keep their own modification times.
+	ModTime time.Time
+}
+
+// Build writes to w a .deb file containing a control tarball made from the contents of controlDir
+// and a data tarball made from the contents of dataDir. controlDir must contain a control file, and
+// may also contain maintainer scripts (e.g. postinst) and other control files (e.g. conffiles).
+//
+// Like dpkg-deb, Build stores the files in the tarballs with paths beginning with "./", in lexical
+// order, and owned by root (regardless of their owners on disk). Regular files, directories and
+// symbolic links are supported.
+func Build(w io.Writer, controlDir, dataDir string, opts BuildOptions) error {
+	if fi, err := os.Stat(filepath.Join(controlDir, "control")); err != nil {
+		return fmt.Errorf("deb: %w", err)
+	} else if !fi.Mode().IsRegular() {
+		return errors.New("deb: control is not a regular file")
+	}
+	modTime := opts.ModTime
+	if modTime.IsZero() {
+		modTime = time.Now()
+	}
+	aw := ar.NewWriter(w, ar.BSD)
+	hdr := &ar.Header{Name: debianBinary, ModTime: modTime, Mode: 0100644, Size: int64(len(formatVersion))}
+	if err := aw.WriteHeader(hdr); err != nil {
+		return err
+	}
+	if _, err := io.WriteString(aw, formatVersion); err != nil {
+		return err
+	}
+	for _, t := range []struct {
+		Name, Dir  string
+		Compressor *Compressor
+	}{
+		{controlTar, controlDir, opts.ControlCompression},
+		{dataTar, dataDir, opts.DataCompression},
+	} {
+		if t.Compressor == nil {
+			t.Compressor = Gzip
+		}
+		if err := writeTarball(aw, t.Name+t.Compressor.Extension, t.Dir, t.Compressor, modTime, opts.ModTime); err != nil {
+			return err
+		}
+	}
+	return aw.Close()
+}
+
+// writeTarball writes an archive member with the given name and modification time, containing a
+// tarball of the contents of dir compressed with c. If fileModTime is non-zero, it is used as the
+// modification time of every file in the tarball. The tarball is written to a temporary file first,
+// since its size must be known before the member's header can be written.
+func writeTarball(aw *ar.Writer, name, dir string, c *Compressor, modTime, fileModTime time.Time) error {
+	tmp, err := os.CreateTemp("", "deb-")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+	var cw io.WriteCloser = nopWriteCloser{tmp}
+	if c.NewWriter != nil {
+		if cw, err = c.NewWriter(tmp); err != nil {
+			return fmt.Errorf("deb: %s: %w", name, err)
+		}
+	}
+	if err := writeTar(cw, dir, fileModTime); err != nil {
+		return fmt.Errorf("deb: %s: %w", name, err)
+	}
+	if err := cw.Close(); err != nil {
+		return fmt.Errorf("deb: %s: %w", name, err)
+	}
+	size, err := tmp.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	if err := aw.WriteHeader(&ar.Header{Name: name, ModTime: modTime, Mode: 0100644, Size: size}); err != nil {
+		return err
+	}
+	_, err = io.Copy(aw, tmp)
+	return err
+}
+
+// writeTar writes a tarball of the contents of dir to w.
+func writeTar(w io.Writer, dir string, modTime time.Time) error {
+	tw := tar.NewWriter(w)
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		fi, err := d.Info()
+		if err != nil {
+			return err
+		}
+		var link string
+		switch {
+		case fi.Mode()&fs.ModeSymlink != 0:
+			if link, err = os.Readlink(path); err != nil {
+				return err
+			}
+		case !fi.Mode().IsRegular() && !fi.IsDir():
+			return fmt.Errorf("%s: unsupported file type %s", path, fi.Mode().Type())
+		}
+		hdr, err := tar.FileInfoHeader(fi, link)
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		hdr.Name = "./"
+		if rel != "." {
+			hdr.Name += filepath.ToSlash(rel)
+			if fi.IsDir() {
+				hdr.Name += "/"
+			}
+		}
+		hdr.Uid, hdr.Gid, hdr.Uname, hdr.Gname = 0, 0, "root", "root"
+		hdr.Format = tar.FormatGNU
+		if !modTime.IsZero() {
+			hdr.ModTime = modTime
+		}
+		hdr.ModTime = hdr.ModTime.Truncate(time.Second)
+		hdr.AccessTime, hdr.ChangeTime = time.Time{}, time.Time{}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		if !fi.Mode().IsRegular() {
+			return nil
+		}
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		_, err = io.Copy(tw, f)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	return tw.Close()
+}
+
+// nopWriteCloser is an io.WriteCloser whose Close method does nothing.
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error {
+	return nil
+}
@@ -0,0 +1,101 @@
+package deb
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/please-build/ar"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeTree creates a control directory and a data tree with the same contents as the ones used to
+// build test_data/hello_gzip.deb.
+func writeTree(t *testing.T) (string, string) {
+	dir := t.TempDir()
+	controlDir, dataDir := filepath.Join(dir, "DEBIAN"), filepath.Join(dir, "data")
+	require.NoError(t, os.MkdirAll(controlDir, 0755))
+	require.NoError(t, os.MkdirAll(filepath.Join(dataDir, "usr/share/hello"), 0755))
+	for _, e := range helloControl[1:] {
+		require.NoError(t, os.WriteFile(filepath.Join(controlDir, e.Name), []byte(e.Data), 0755))
+	}
+	require.NoError(t, os.WriteFile(filepath.Join(dataDir, "usr/share/hello/hello.txt"), []byte("hello\n"), 0644))
+	require.NoError(t, os.Symlink("hello.txt", filepath.Join(dataDir, "usr/share/hello/link.txt")))
+	return controlDir, dataDir
+}
+
+func TestBuild(t *testing.T) {
+	controlDir, dataDir := writeTree(t)
+	modTime := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	var buf bytes.Buffer
+	require.NoError(t, Build(&buf, controlDir, dataDir, BuildOptions{ModTime: modTime}))
+
+	reader, err := ar.NewReader(bytes.NewReader(buf.Bytes()))
+	require.NoError(t, err)
+	for _, name := range []string{"debian-binary", "control.tar.gz", "data.tar.gz"} {
+		hdr, err := reader.Next()
+		require.NoError(t, err)
+		assert.Equal(t, name, hdr.Name)
+		assert.True(t, modTime.Equal(hdr.ModTime))
+		assert.Equal(t, 0, hdr.Uid)
+		assert.Equal(t, int64(0100644), hdr.Mode)
+	}
+
+	rd, err := NewReader(bytes.NewReader(buf.Bytes()))
+	require.NoError(t, err)
+	defer rd.Close()
+	assert.Equal(t, "2.0", rd.Version())
+	control, err := rd.Control()
+	require.NoError(t, err)
+	assert.Equal(t, helloControl, readTar(t, control))
+	data, err := rd.Data()
+	require.NoError(t, err)
+	for _, expected := range helloData {
+		hdr, err := data.Next()
+		require.NoError(t, err)
+		assert.Equal(t, expected.Name, hdr.Name)
+		assert.True(t, modTime.Equal(hdr.ModTime))
+		assert.Equal(t, "root", hdr.Uname)
+		assert.Equal(t, 0, hdr.Gid)
+	}
+
+	// Packages built with a fixed modification time are reproducible.
+	var again bytes.Buffer
+	require.NoError(t, Build(&again, controlDir, dataDir, BuildOptions{ModTime: modTime}))
+	assert.Equal(t, buf.Bytes(), again.Bytes())
+}
+
+func TestBuildCompression(t *testing.T) {
+	controlDir, dataDir := writeTree(t)
+	var buf bytes.Buffer
+	require.NoError(t, Build(&buf, controlDir, dataDir, BuildOptions{
+		ControlCompression: Uncompressed,
+		DataCompression:    Gzip,
+	}))
+	reader, err := ar.NewReader(bytes.NewReader(buf.Bytes()))
+	require.NoError(t, err)
+	for _, name := range []string{"debian-binary", "control.tar", "data.tar.gz"} {
+		hdr, err := reader.Next()
+		require.NoError(t, err)
+		assert.Equal(t, name, hdr.Name)
+	}
+
+	rd, err := NewReader(bytes.NewReader(buf.Bytes()))
+	require.NoError(t, err)
+	control, err := rd.Control()
+	require.NoError(t, err)
+	assert.Equal(t, helloControl, readTar(t, control))
+	data, err := rd.Data()
+	require.NoError(t, err)
+	assert.Equal(t, helloData, readTar(t, data))
+}
+
+func TestBuildMissingControl(t *testing.T) {
+	controlDir, dataDir := writeTree(t)
+	require.NoError(t, os.Remove(filepath.Join(controlDir, "control")))
+	var buf bytes.Buffer
+	assert.Error(t, Build(&buf, controlDir, dataDir, BuildOptions{}))
+}
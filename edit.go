@@ -0,0 +1,141 @@
+package ar
+
+import (
+	"errors"
+	"io"
+)
+
+// editKind identifies the kind of an EditOp.
+type editKind int
+
+const (
+	editDelete editKind = iota
+	editReplace
+	editInsertBefore
+	editInsertAfter
+	editMoveToEnd
+)
+
+// EditOp is an operation that Edit performs on an archive. Each operation targets the first member
+// of the archive with a given name, at the time the operation is performed.
+type EditOp struct {
+	kind editKind
+
+	// name is the name of the archive member targeted by the operation.
+	name string
+
+	// hdr and data are the header and data section of the member inserted by the operation, if any.
+	// The data section consists of the first hdr.Size bytes readable from data, or hdr.Size zero bytes
+	// if data is nil (which is useful for members of thin archives, whose data sections aren't stored
+	// in the archive).
+	hdr  Header
+	data io.ReaderAt
+}
+
+// DeleteMember returns an EditOp that deletes the named archive member (like GNU ar's "d" command).
+func DeleteMember(name string) EditOp {
+	return EditOp{kind: editDelete, name: name}
+}
+
+// ReplaceMember returns an EditOp that replaces the named archive member with a member with the
+// given header, whose data section consists of the first hdr.Size bytes of data. The new member
+// takes the place of the replaced member in the archive (like GNU ar's "r" command).
+func ReplaceMember(name string, hdr *Header, data io.ReaderAt) EditOp {
+	return EditOp{kind: editReplace, name: name, hdr: *hdr, data: data}
+}
+
+// InsertMemberBefore returns an EditOp that inserts a member with the given header, whose data
+// section consists of the first hdr.Size bytes of data, immediately before the named archive member
+// (like GNU ar's "b" modifier).
+func InsertMemberBefore(name string, hdr *Header, data io.ReaderAt) EditOp {
+	return EditOp{kind: editInsertBefore, name: name, hdr: *hdr, data: data}
+}
+
+// InsertMemberAfter returns an EditOp that inserts a member with the given header, whose data
+// section consists of the first hdr.Size bytes of data, immediately after the named archive member
+// (like GNU ar's "a" modifier).
+func InsertMemberAfter(name string, hdr *Header, data io.ReaderAt) EditOp {
+	return EditOp{kind: editInsertAfter, name: name, hdr: *hdr, data: data}
+}
+
+// MoveMemberToEnd returns an EditOp that moves the named archive member to the end of the archive
+// (like GNU ar's "m" command).
+func MoveMemberToEnd(name string) EditOp {
+	return EditOp{kind: editMoveToEnd, name: name}
+}
+
+// EditOptions specifies optional behaviour for Edit.
+type EditOptions struct {
+	// WriterOptions specifies how the edited archive is written. If the original archive is a thin
+	// archive, Thin is set automatically. If the original archive has a symbol table, SymbolIndex is
+	// set automatically, so that the symbol table is regenerated to reflect the edited members.
+	WriterOptions
+
+	// Resolver opens the external files containing the data sections of the members of a thin
+	// archive. It is only needed if the archive's symbol table must be regenerated.
+	Resolver Resolver
+}
+
+// Edit writes to w a copy of the archive a, using the same variant of the ar file format, with the
+// given operations performed on its members in order. The data sections of members that aren't
+// inserted by the operations are copied directly from the original archive file. It returns an
+// *ErrFileName if an operation targets a member that doesn't exist.
+func Edit(w io.Writer, a *Archive, ops []EditOp, opts EditOptions) error {
+	opts.Thin = a.Thin()
+	if a.Symbols() != nil {
+		opts.SymbolIndex = true
+	}
+	s := &spool{max: opts.MaxMemory}
+	if s.max == 0 {
+		s.max = DefaultMaxMemory
+	}
+	defer s.Close()
+	members, err := archiveMembers(a, opts.SymbolIndex, opts.Resolver, s)
+	if err != nil {
+		return err
+	}
+	for _, op := range ops {
+		if members, err = op.apply(members); err != nil {
+			return err
+		}
+	}
+	out := newWriter(w, a.Variant(), opts.WriterOptions)
+	out.coff = a.COFFLinkerMember() != nil
+	if err := out.writeArchive(members, nil); err != nil {
+		return err
+	}
+	return out.Close()
+}
+
+// apply performs the operation on the given list of archive members, returning the edited list.
+func (op EditOp) apply(members []member) ([]member, error) {
+	i := -1
+	for j, m := range members {
+		if m.hdr.Name == op.name {
+			i = j
+			break
+		}
+	}
+	if i < 0 {
+		return nil, &ErrFileName{Name: op.name, Err: errors.New("no such archive member")}
+	}
+	data := op.data
+	if data == nil {
+		data = zeroReaderAt{}
+	}
+	inserted := member{hdr: op.hdr, data: io.NewSectionReader(data, 0, op.hdr.Size)}
+	switch op.kind {
+	case editDelete:
+		return append(members[:i], members[i+1:]...), nil
+	case editReplace:
+		members[i] = inserted
+	case editInsertBefore:
+		members = append(members[:i], append([]member{inserted}, members[i:]...)...)
+	case editInsertAfter:
+		members = append(members[:i+1], append([]member{inserted}, members[i+1:]...)...)
+	case editMoveToEnd:
+		m := members[i]
+		members = append(append(members[:i], members[i+1:]...), m)
+	}
+	return members, nil
+}
@@ -0,0 +1,106 @@
+package ar
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// editArchive edits the archive at the given path, returning the path to the edited archive.
+func editArchive(t *testing.T, path string, ops ...EditOp) string {
+	out := filepath.Join(t.TempDir(), "edited.a")
+	f, err := os.Create(out)
+	require.NoError(t, err)
+	defer f.Close()
+	require.NoError(t, Edit(f, openArchive(t, path), ops, EditOptions{}))
+	return out
+}
+
+func TestEdit(t *testing.T) {
+	for _, tc := range []struct {
+		Description string
+		Variant     Variant
+		ArchivePath string
+	}{
+		{"BSD format", BSD, "./test_data/long_filenames_bsd.a"},
+		{"GNU format", GNU, "./test_data/long_filenames_gnu.a"},
+	} {
+		t.Run(tc.Description, func(t *testing.T) {
+			data := strings.NewReader("new data")
+			hdr := func(name string) *Header {
+				return &Header{Name: name, Mode: 0644, Size: data.Size()}
+			}
+			out := editArchive(t, tc.ArchivePath,
+				DeleteMember("1"),
+				ReplaceMember("2x", hdr("a_replacement_with_a_long_name"), data),
+				InsertMemberBefore("4xxx", hdr("before"), data),
+				InsertMemberAfter("4xxx", hdr("after"), data),
+				MoveMemberToEnd("3xx"),
+				DeleteMember("20xxxxxxxxxxxxxxxxxx"),
+			)
+			archive := openArchive(t, out)
+			assert.Equal(t, tc.Variant, archive.Variant())
+			var names []string
+			for _, f := range archive.Files {
+				names = append(names, f.Name)
+			}
+			assert.Equal(t, []string{
+				"a_replacement_with_a_long_name", "before", "4xxx", "after", "5xxxx", "6xxxxx", "7xxxxxx",
+				"8xxxxxxx", "9xxxxxxxx", "10xxxxxxxx", "11xxxxxxxxx", "12xxxxxxxxxx", "13xxxxxxxxxxx",
+				"14xxxxxxxxxxxx", "15xxxxxxxxxxxxx", "16xxxxxxxxxxxxxx", "17xxxxxxxxxxxxxxx", "18xxxxxxxxxxxxxxxx",
+				"19xxxxxxxxxxxxxxxxx", "3xx",
+			}, names)
+			for _, f := range archive.Files {
+				r, err := f.Open()
+				require.NoError(t, err)
+				b, err := io.ReadAll(r)
+				require.NoError(t, err)
+				switch f.Name {
+				case "a_replacement_with_a_long_name", "before", "after":
+					assert.Equal(t, "new data", string(b))
+				default:
+					assert.Equal(t, fmt.Sprintf("The name of this file contains %d character(s).\n", len(f.Name)), string(b))
+				}
+			}
+		})
+	}
+}
+
+func TestEditMissingMember(t *testing.T) {
+	var buf bytes.Buffer
+	err := Edit(&buf, openArchive(t, "./test_data/hello.a"), []EditOp{DeleteMember("hello.txt"), DeleteMember("hello.txt")}, EditOptions{})
+	var nameErr *ErrFileName
+	require.ErrorAs(t, err, &nameErr)
+	assert.Equal(t, "hello.txt", nameErr.Name)
+}
+
+func TestEditRegeneratesSymbolTable(t *testing.T) {
+	out := editArchive(t, "./test_data/symbols_gnu.a", DeleteMember("add.o"))
+	archive := openArchive(t, out)
+	require.Len(t, archive.Files, 1)
+	assert.Equal(t, []Symbol{{Name: "sub", Offset: archive.Files[0].HeaderOffset()}}, archive.Symbols())
+}
+
+func TestEditThin(t *testing.T) {
+	var buf bytes.Buffer
+	archive := openArchive(t, "./test_data/thin.a")
+	ops := []EditOp{DeleteMember("objs/h.txt"), MoveMemberToEnd("objs/add.o")}
+	// The archive has a symbol table, which can't be regenerated without the members' data.
+	assert.ErrorIs(t, Edit(&buf, archive, ops, EditOptions{}), ErrNoResolver)
+
+	require.NoError(t, Edit(&buf, archive, ops, EditOptions{Resolver: DirResolver("./test_data")}))
+	edited, err := NewArchive(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	require.NoError(t, err)
+	assert.True(t, edited.Thin())
+	require.Len(t, edited.Files, 2)
+	assert.Equal(t, "objs/sub/a_long_object_name.o", edited.Files[0].Name)
+	assert.Equal(t, "objs/add.o", edited.Files[1].Name)
+	assert.Equal(t, edited.Files[1], edited.SymbolTable()["add"])
+}
@@ -13,16 +13,36 @@ var (
 	// ErrInvalidGlobalHeader indicates that the archive file is invalid because its global
 	// header is malformed (i.e., not the string "!<arch>\n").
 	ErrInvalidGlobalHeader = errors.New("ar: invalid global header")
+
+	// ErrNoResolver indicates that the data section of a member of a thin archive could not be read,
+	// because it is stored in an external file and no Resolver was provided to open it.
+	ErrNoResolver = errors.New("ar: no resolver for thin archive member")
 )
 
+// The error types below record the location in the archive file at which the error was detected:
+// Offset is the byte offset of the header of the archive member being read, relative to the start of
+// the archive file, and Index is the zero-based position of that member in the archive, counting
+// special members such as symbol tables and string tables. Offset is 0 if the error isn't associated
+// with a particular archive member.
+
+// location describes the location of an archive member in an error message.
+func location(index int, offset int64) string {
+	if offset == 0 {
+		return ""
+	}
+	return fmt.Sprintf(" (member %d at offset %d)", index, offset)
+}
+
 // ErrStringTable indicates a problem with the string table in archives that use the GNU variant of
 // the file format.
 type ErrStringTable struct {
-	Err error
+	Offset int64
+	Index  int
+	Err    error
 }
 
 func (e *ErrStringTable) Error() string {
-	return fmt.Sprintf("ar: string table: %s", e.Err)
+	return fmt.Sprintf("ar: string table%s: %s", location(e.Index, e.Offset), e.Err)
 }
 
 func (e *ErrStringTable) Unwrap() error {
@@ -31,14 +51,82 @@ func (e *ErrStringTable) Unwrap() error {
 
 // ErrFileName indicates a problem with the file name in one of the archive's file headers.
 type ErrFileName struct {
-	Name string
-	Err  error
+	Name   string
+	Offset int64
+	Index  int
+	Err    error
 }
 
 func (e *ErrFileName) Error() string {
-	return fmt.Sprintf("ar: archive member '%s': %s", e.Name, e.Err)
+	return fmt.Sprintf("ar: archive member '%s'%s: %s", e.Name, location(e.Index, e.Offset), e.Err)
 }
 
 func (e *ErrFileName) Unwrap() error {
 	return e.Err
 }
+
+// ErrSymbolTable indicates a problem with the archive's symbol table.
+type ErrSymbolTable struct {
+	Offset int64
+	Index  int
+	Err    error
+}
+
+func (e *ErrSymbolTable) Error() string {
+	return fmt.Sprintf("ar: symbol table%s: %s", location(e.Index, e.Offset), e.Err)
+}
+
+func (e *ErrSymbolTable) Unwrap() error {
+	return e.Err
+}
+
+// ErrHeader indicates that one of the archive's file headers is malformed. It is only returned by
+// Readers created with the Strict option.
+type ErrHeader struct {
+	Offset int64
+	Index  int
+	Err    error
+}
+
+func (e *ErrHeader) Error() string {
+	return fmt.Sprintf("ar: header%s: %s", location(e.Index, e.Offset), e.Err)
+}
+
+func (e *ErrHeader) Unwrap() error {
+	return e.Err
+}
+
+// ErrLimit indicates that reading an archive would exceed one of the resource limits specified in the
+// ReaderOptions used to create the Reader.
+type ErrLimit struct {
+	// Resource describes the limited resource, e.g. "string table size".
+	Resource string
+
+	// Value is the amount of the resource that the archive requires, which may only be a lower bound.
+	Value int64
+
+	// Limit is the maximum amount of the resource permitted by the Reader's options.
+	Limit int64
+
+	Offset int64
+	Index  int
+}
+
+func (e *ErrLimit) Error() string {
+	return fmt.Sprintf("ar: %s %d exceeds limit of %d%s", e.Resource, e.Value, e.Limit, location(e.Index, e.Offset))
+}
+
+// ErrMRIScript indicates a problem with an MRI script, either when parsing or running it.
+type ErrMRIScript struct {
+	// Line is the line number in the script of the command that caused the problem.
+	Line int
+	Err  error
+}
+
+func (e *ErrMRIScript) Error() string {
+	return fmt.Sprintf("ar: MRI script line %d: %s", e.Line, e.Err)
+}
+
+func (e *ErrMRIScript) Unwrap() error {
+	return e.Err
+}
@@ -0,0 +1,138 @@
+package ar
+
+import (
+	"errors"
+	"io/fs"
+	"path"
+	"time"
+)
+
+// sysStat, if non-nil, populates h from system-dependent fields of fi.
+var sysStat func(fi fs.FileInfo, h *Header) error
+
+// FileInfo returns an fs.FileInfo for the Header.
+func (h *Header) FileInfo() fs.FileInfo {
+	return headerFileInfo{h}
+}
+
+// FileInfoHeader creates a partially-populated Header from fi. Name is set to the base name of the
+// file described by fi, and Size, Mode and ModTime are set from the corresponding fs.FileInfo
+// fields. On Unix systems, Uid and Gid are also set if fi describes a file on the local file system
+// (i.e. if it was returned by os.Stat or similar); if fi was returned by Header.FileInfo, they are
+// set from the original Header.
+//
+// Archive members can only represent regular files; FileInfoHeader returns an error if fi describes
+// any other kind of file.
+func FileInfoHeader(fi fs.FileInfo) (*Header, error) {
+	if fi == nil {
+		return nil, errors.New("ar: FileInfo is nil")
+	}
+	if !fi.Mode().IsRegular() {
+		return nil, errors.New("ar: archive members must be regular files")
+	}
+	h := &Header{
+		Name:    fi.Name(),
+		ModTime: fi.ModTime(),
+		Mode:    unixMode(fi.Mode()),
+		Size:    fi.Size(),
+	}
+	if sys, ok := fi.Sys().(*Header); ok {
+		h.Uid = sys.Uid
+		h.Gid = sys.Gid
+	} else if sysStat != nil {
+		if err := sysStat(fi, h); err != nil {
+			return nil, err
+		}
+	}
+	return h, nil
+}
+
+// headerFileInfo is an fs.FileInfo describing an archive member.
+type headerFileInfo struct {
+	h *Header
+}
+
+func (fi headerFileInfo) Name() string {
+	return path.Base(fi.h.Name)
+}
+
+func (fi headerFileInfo) Size() int64 {
+	return fi.h.Size
+}
+
+func (fi headerFileInfo) Mode() fs.FileMode {
+	return fileMode(fi.h.Mode)
+}
+
+func (fi headerFileInfo) ModTime() time.Time {
+	return fi.h.ModTime
+}
+
+func (fi headerFileInfo) IsDir() bool {
+	return fi.Mode().IsDir()
+}
+
+// Sys returns the archive member's *Header.
+func (fi headerFileInfo) Sys() interface{} {
+	return fi.h
+}
+
+// Unix file mode bits, as stored in archive member headers.
+const (
+	modeSetuid   = 04000
+	modeSetgid   = 02000
+	modeSticky   = 01000
+	modeTypeDir  = 040000
+	modeTypeFIFO = 010000
+	modeTypeReg  = 0100000
+	modeTypeLink = 0120000
+	modeTypeBlk  = 060000
+	modeTypeChr  = 020000
+	modeTypeSock = 0140000
+	modeTypeMask = 0170000
+)
+
+// fileMode converts a Unix file mode, as stored in an archive member header, to an fs.FileMode.
+func fileMode(mode int64) fs.FileMode {
+	m := fs.FileMode(mode).Perm()
+	if mode&modeSetuid != 0 {
+		m |= fs.ModeSetuid
+	}
+	if mode&modeSetgid != 0 {
+		m |= fs.ModeSetgid
+	}
+	if mode&modeSticky != 0 {
+		m |= fs.ModeSticky
+	}
+	switch mode & modeTypeMask {
+	case modeTypeDir:
+		m |= fs.ModeDir
+	case modeTypeFIFO:
+		m |= fs.ModeNamedPipe
+	case modeTypeLink:
+		m |= fs.ModeSymlink
+	case modeTypeBlk:
+		m |= fs.ModeDevice
+	case modeTypeChr:
+		m |= fs.ModeDevice | fs.ModeCharDevice
+	case modeTypeSock:
+		m |= fs.ModeSocket
+	}
+	return m
+}
+
+// unixMode converts an fs.FileMode describing a regular file to a Unix file mode, as stored in an
+// archive member header.
+func unixMode(m fs.FileMode) int64 {
+	mode := int64(m.Perm()) | modeTypeReg
+	if m&fs.ModeSetuid != 0 {
+		mode |= modeSetuid
+	}
+	if m&fs.ModeSetgid != 0 {
+		mode |= modeSetgid
+	}
+	if m&fs.ModeSticky != 0 {
+		mode |= modeSticky
+	}
+	return mode
+}
@@ -0,0 +1,76 @@
+package ar
+
+import (
+	"io/fs"
+	"os"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHeaderFileInfo(t *testing.T) {
+	for _, tc := range []struct {
+		Mode     int64
+		FileMode fs.FileMode
+	}{
+		{0100644, 0644},
+		{0644, 0644},
+		{0104755, fs.ModeSetuid | 0755},
+		{0102755, fs.ModeSetgid | 0755},
+		{0101777, fs.ModeSticky | 0777},
+		{040755, fs.ModeDir | 0755},
+		{0120777, fs.ModeSymlink | 0777},
+	} {
+		hdr := &Header{
+			Name:    "hello.txt",
+			ModTime: time.Unix(1361157466, 0),
+			Mode:    tc.Mode,
+			Size:    13,
+		}
+		fi := hdr.FileInfo()
+		assert.Equal(t, "hello.txt", fi.Name())
+		assert.Equal(t, int64(13), fi.Size())
+		assert.Equal(t, tc.FileMode, fi.Mode(), "mode %o", tc.Mode)
+		assert.Equal(t, time.Unix(1361157466, 0), fi.ModTime())
+		assert.Equal(t, tc.FileMode.IsDir(), fi.IsDir())
+		assert.Same(t, hdr, fi.Sys())
+	}
+}
+
+func TestFileInfoHeader(t *testing.T) {
+	fi, err := os.Stat("./test_data/hello.txt")
+	require.NoError(t, err)
+	hdr, err := FileInfoHeader(fi)
+	require.NoError(t, err)
+	assert.Equal(t, "hello.txt", hdr.Name)
+	assert.Equal(t, int64(13), hdr.Size)
+	assert.Equal(t, 0100000|int64(fi.Mode().Perm()), hdr.Mode)
+	assert.Equal(t, fi.ModTime(), hdr.ModTime)
+	if runtime.GOOS != "windows" {
+		assert.Equal(t, os.Getuid(), hdr.Uid)
+	}
+
+	_, err = FileInfoHeader(nil)
+	assert.Error(t, err)
+	fi, err = os.Stat("./test_data")
+	require.NoError(t, err)
+	_, err = FileInfoHeader(fi)
+	assert.Error(t, err)
+}
+
+func TestFileInfoHeaderRoundTrip(t *testing.T) {
+	hdr := &Header{
+		Name:    "hello.txt",
+		ModTime: time.Unix(1361157466, 0),
+		Uid:     501,
+		Gid:     20,
+		Mode:    0104755,
+		Size:    13,
+	}
+	actual, err := FileInfoHeader(hdr.FileInfo())
+	require.NoError(t, err)
+	assert.Equal(t, hdr, actual)
+}
@@ -0,0 +1,212 @@
+package ar
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"path"
+	"sort"
+	"time"
+)
+
+// fsEntry is a file or directory in the file system view of an Archive.
+type fsEntry struct {
+	// name is the entry's full path within the file system.
+	name string
+
+	// file is the archive member represented by the entry, or nil if the entry is a directory.
+	file *File
+}
+
+// initFS builds the file system view of the archive. Archive members are visible as files in the
+// file system's root directory, unless their names contain "/" (which is only possible in thin
+// archives), in which case directories are synthesised as necessary to contain them. Members whose
+// names aren't valid paths are not visible, and if more than one member has the same name, only the
+// first is visible.
+func (a *Archive) initFS() {
+	a.fsOnce.Do(func() {
+		byName := map[string]*fsEntry{".": {name: "."}}
+		for _, f := range a.Files {
+			if !fs.ValidPath(f.Name) || f.Name == "." {
+				continue
+			}
+			if _, present := byName[f.Name]; present {
+				continue
+			}
+			// Skip members whose names conflict with files that have already been added as a directory.
+			conflict := false
+			for dir := path.Dir(f.Name); dir != "."; dir = path.Dir(dir) {
+				if e, present := byName[dir]; present && e.file != nil {
+					conflict = true
+					break
+				}
+			}
+			if conflict {
+				continue
+			}
+			byName[f.Name] = &fsEntry{name: f.Name, file: f}
+			for dir := path.Dir(f.Name); dir != "."; dir = path.Dir(dir) {
+				byName[dir] = &fsEntry{name: dir}
+			}
+		}
+		a.fsEntries = map[string]*fsEntry{}
+		a.fsChildren = map[string][]*fsEntry{}
+		for name, e := range byName {
+			a.fsEntries[name] = e
+			if name != "." {
+				dir := path.Dir(name)
+				a.fsChildren[dir] = append(a.fsChildren[dir], e)
+			}
+		}
+		for _, children := range a.fsChildren {
+			sort.Slice(children, func(i, j int) bool { return children[i].name < children[j].name })
+		}
+	})
+}
+
+// lookup returns the file system entry with the given name, or an error describing why it doesn't
+// exist.
+func (a *Archive) lookup(op, name string) (*fsEntry, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: op, Path: name, Err: fs.ErrInvalid}
+	}
+	a.initFS()
+	e, ok := a.fsEntries[name]
+	if !ok {
+		return nil, &fs.PathError{Op: op, Path: name, Err: fs.ErrNotExist}
+	}
+	return e, nil
+}
+
+// Open opens the named archive member for reading, making Archive an fs.FS. The archive's members
+// are files in the file system's root directory; the file system is read-only.
+func (a *Archive) Open(name string) (fs.File, error) {
+	e, err := a.lookup("open", name)
+	if err != nil {
+		return nil, err
+	}
+	if e.file == nil {
+		return &fsDir{entry: e, entries: a.fsChildren[e.name]}, nil
+	}
+	r, err := e.file.Open()
+	if err != nil {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: err}
+	}
+	return &fsFile{SectionReader: r, entry: e}, nil
+}
+
+// ReadDir reads the named directory in the file system view of the archive, returning its entries
+// sorted by file name.
+func (a *Archive) ReadDir(name string) ([]fs.DirEntry, error) {
+	e, err := a.lookup("readdir", name)
+	if err != nil {
+		return nil, err
+	}
+	if e.file != nil {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: errors.New("not a directory")}
+	}
+	children := a.fsChildren[e.name]
+	entries := make([]fs.DirEntry, len(children))
+	for i, child := range children {
+		entries[i] = fs.FileInfoToDirEntry(child.stat())
+	}
+	return entries, nil
+}
+
+// Stat returns an fs.FileInfo describing the named file in the file system view of the archive.
+func (a *Archive) Stat(name string) (fs.FileInfo, error) {
+	e, err := a.lookup("stat", name)
+	if err != nil {
+		return nil, err
+	}
+	return e.stat(), nil
+}
+
+// stat returns an fs.FileInfo describing the entry.
+func (e *fsEntry) stat() fs.FileInfo {
+	if e.file == nil {
+		return dirFileInfo(path.Base(e.name))
+	}
+	return e.file.FileInfo()
+}
+
+// fsFile is an archive member that has been opened via Archive.Open.
+type fsFile struct {
+	*io.SectionReader
+	entry *fsEntry
+}
+
+func (f *fsFile) Stat() (fs.FileInfo, error) {
+	return f.entry.stat(), nil
+}
+
+func (f *fsFile) Close() error {
+	return nil
+}
+
+// fsDir is a directory that has been opened via Archive.Open.
+type fsDir struct {
+	entry *fsEntry
+
+	// entries is the list of the directory's entries.
+	entries []*fsEntry
+
+	// offset is the index in entries of the next entry to be returned by ReadDir.
+	offset int
+}
+
+func (d *fsDir) Stat() (fs.FileInfo, error) {
+	return d.entry.stat(), nil
+}
+
+func (d *fsDir) Read([]byte) (int, error) {
+	return 0, &fs.PathError{Op: "read", Path: d.entry.name, Err: errors.New("is a directory")}
+}
+
+func (d *fsDir) Close() error {
+	return nil
+}
+
+func (d *fsDir) ReadDir(n int) ([]fs.DirEntry, error) {
+	remaining := d.entries[d.offset:]
+	if n > 0 && len(remaining) == 0 {
+		return nil, io.EOF
+	}
+	if n > 0 && n < len(remaining) {
+		remaining = remaining[:n]
+	}
+	d.offset += len(remaining)
+	entries := make([]fs.DirEntry, len(remaining))
+	for i, e := range remaining {
+		entries[i] = fs.FileInfoToDirEntry(e.stat())
+	}
+	return entries, nil
+}
+
+// dirFileInfo is an fs.FileInfo describing a directory synthesised in the file system view of an
+// archive.
+type dirFileInfo string
+
+func (fi dirFileInfo) Name() string {
+	return string(fi)
+}
+
+func (fi dirFileInfo) Size() int64 {
+	return 0
+}
+
+func (fi dirFileInfo) Mode() fs.FileMode {
+	return fs.ModeDir | 0555
+}
+
+func (fi dirFileInfo) ModTime() time.Time {
+	return time.Time{}
+}
+
+func (fi dirFileInfo) IsDir() bool {
+	return true
+}
+
+func (fi dirFileInfo) Sys() interface{} {
+	return nil
+}
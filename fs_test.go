@@ -0,0 +1,77 @@
+package ar
+
+import (
+	"bytes"
+	"io/fs"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFS(t *testing.T) {
+	for _, tc := range []struct {
+		ArchivePath string
+		Files       []string
+	}{
+		{"./test_data/hello.a", []string{"hello.txt"}},
+		{"./test_data/long_filenames_bsd.a", []string{"1", "9xxxxxxxx", "20xxxxxxxxxxxxxxxxxx"}},
+		{"./test_data/long_filenames_gnu.a", []string{"1", "9xxxxxxxx", "20xxxxxxxxxxxxxxxxxx"}},
+		{"./test_data/symbols_gnu.a", []string{"add.o", "sub.o"}},
+	} {
+		t.Run(tc.ArchivePath, func(t *testing.T) {
+			archive := openArchive(t, tc.ArchivePath)
+			require.NoError(t, fstest.TestFS(archive, tc.Files...))
+		})
+	}
+}
+
+func TestFSFileInfo(t *testing.T) {
+	archive := openArchive(t, "./test_data/hello.a")
+	fi, err := fs.Stat(archive, "hello.txt")
+	require.NoError(t, err)
+	assert.Equal(t, "hello.txt", fi.Name())
+	assert.Equal(t, int64(13), fi.Size())
+	assert.Equal(t, fs.FileMode(0644), fi.Mode())
+	assert.Equal(t, time.Unix(1361157466, 0), fi.ModTime())
+	assert.False(t, fi.IsDir())
+	assert.Equal(t, &archive.Files[0].Header, fi.Sys())
+
+	b, err := fs.ReadFile(archive, "hello.txt")
+	require.NoError(t, err)
+	assert.Equal(t, "Hello world!\n", string(b))
+
+	_, err = archive.Open("missing.txt")
+	assert.ErrorIs(t, err, fs.ErrNotExist)
+	_, err = archive.Open("../hello.txt")
+	assert.ErrorIs(t, err, fs.ErrInvalid)
+}
+
+func TestFSDirectories(t *testing.T) {
+	// Member names can only contain "/" in thin archives, which refer to files outside of the archive,
+	// but the file system view of the archive doesn't care where the members' data comes from.
+	data := []byte("abcdef")
+	archive := &Archive{r: bytes.NewReader(data)}
+	for i, name := range []string{"top.o", "lib/a.o", "lib/sub/b.o", "lib/a.o", "top.o/c.o", "/abs.o"} {
+		archive.Files = append(archive.Files, &File{
+			Header:     Header{Name: name, Mode: 0100644, Size: 1},
+			archive:    archive,
+			dataOffset: int64(i),
+		})
+	}
+	require.NoError(t, fstest.TestFS(archive, "top.o", "lib/a.o", "lib/sub/b.o"))
+	entries, err := fs.ReadDir(archive, "lib")
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	assert.Equal(t, "a.o", entries[0].Name())
+	assert.False(t, entries[0].IsDir())
+	assert.Equal(t, "sub", entries[1].Name())
+	assert.True(t, entries[1].IsDir())
+	b, err := fs.ReadFile(archive, "lib/a.o")
+	require.NoError(t, err)
+	assert.Equal(t, "b", string(b))
+	_, err = fs.Stat(archive, "top.o/c.o")
+	assert.ErrorIs(t, err, fs.ErrNotExist)
+}
@@ -0,0 +1,405 @@
+package ar
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"strings"
+)
+
+// Machine types of the architectures for which WriteImportLibrary can write import libraries.
+const (
+	MachineI386  uint16 = 0x14c
+	MachineAMD64 uint16 = 0x8664
+	MachineARMNT uint16 = 0x1c4
+	MachineARM64 uint16 = 0xaa64
+)
+
+// Export is a symbol exported by a DLL, as listed in the EXPORTS section of a module-definition
+// (.def) file.
+type Export struct {
+	// Name is the name of the symbol, as it appears in the object files that import it. On 32-bit x86
+	// this includes the leading "_" with which C compilers decorate names (e.g. "_HelloWorld" for a
+	// function exported by the DLL as "HelloWorld").
+	Name string
+
+	// Ordinal is the symbol's ordinal in the DLL's export table. If NoName is false, it is used as a
+	// hint for the index of the symbol's name in the DLL's export name table.
+	Ordinal uint16
+
+	// NoName indicates that the symbol is imported by its ordinal rather than by its name.
+	NoName bool
+
+	// Data indicates that the symbol is a variable rather than a function, which means that the
+	// import library doesn't define a thunk for it; it can only be accessed via its import address
+	// table entry, "__imp_" followed by Name.
+	Data bool
+
+	// Constant indicates that the symbol is a constant rather than a function.
+	Constant bool
+}
+
+// WriteImportLibrary writes to w a Windows import library (.lib file) for the named DLL, which
+// exports the given symbols, for the given machine type (one of the Machine constants). This allows
+// programs that link against the DLL to be built without access to Microsoft's tools.
+//
+// The import library is written in the format produced by Microsoft's lib.exe: its first and second
+// linker members are followed by a "//" string table (if the DLL's name is more than 15 bytes long),
+// three COFF object files defining the DLL's import descriptor and the terminators of the import
+// tables, and a short import object for each exported symbol. The archive is deterministic: all of
+// its members have a modification time of 0.
+func WriteImportLibrary(w io.Writer, dll string, machine uint16, exports []Export) error {
+	relocation, ok := coffImageRelRelocations[machine]
+	if !ok {
+		return fmt.Errorf("ar: unsupported machine type 0x%x", machine)
+	}
+	if dll == "" {
+		return errors.New("ar: empty DLL name")
+	}
+	// Each member is an object file, whose data section is followed by the names of the symbols it
+	// defines.
+	type object struct {
+		Data    []byte
+		Symbols []string
+	}
+	library := strings.TrimSuffix(dll, path.Ext(dll))
+	descriptor := "__IMPORT_DESCRIPTOR_" + library
+	nullThunk := "\x7f" + library + "_NULL_THUNK_DATA"
+	objects := []object{
+		{coffImportDescriptor(machine, relocation, dll, descriptor, nullThunk), []string{descriptor}},
+		{coffNullImportDescriptor(machine), []string{coffNullImportDescriptorName}},
+		{coffNullThunk(machine, nullThunk), []string{nullThunk}},
+	}
+	for _, export := range exports {
+		if export.Name == "" {
+			return errors.New("ar: export with empty name")
+		}
+		if export.Data && export.Constant {
+			return fmt.Errorf("ar: export '%s' is both data and a constant", export.Name)
+		}
+		obj := &ImportObject{Machine: machine, Type: ImportCode, NameType: ImportName, Symbol: export.Name, DLL: dll}
+		switch {
+		case export.Data:
+			obj.Type = ImportData
+		case export.Constant:
+			obj.Type = ImportConst
+		}
+		// The names of symbols exported by 32-bit x86 DLLs don't include the leading "_" that C
+		// compilers add to them, unless they're also decorated with their arguments' size (e.g.
+		// "_HelloWorld@4"), in which case they're exported with their full names.
+		if machine == MachineI386 && strings.HasPrefix(export.Name, "_") && !strings.Contains(export.Name, "@") {
+			obj.NameType = ImportNameNoPrefix
+		}
+		if export.NoName {
+			if export.Ordinal == 0 {
+				return fmt.Errorf("ar: export '%s' is imported by ordinal, but has no ordinal", export.Name)
+			}
+			obj.NameType = ImportOrdinal
+			obj.Ordinal = export.Ordinal
+		} else {
+			obj.Hint = export.Ordinal
+		}
+		objects = append(objects, object{obj.encode(), obj.symbols()})
+	}
+
+	// All of the members are named after the DLL. As with the GNU variant, names more than 15 bytes
+	// long are stored in the string table, but are terminated by a NUL byte rather than "/\n".
+	var stringTable []byte
+	name := dll
+	if len(dll) > 15 {
+		stringTable = append([]byte(dll), 0)
+		name = "/0"
+	}
+
+	// The linker members can only be written once the offsets of the members are known, which in turn
+	// depend on the sizes of the linker members.
+	memberSize := func(size int) int64 {
+		return int64(HEADER_BYTE_SIZE + size + size%2)
+	}
+	var symbols []Symbol
+	var symbolNamesSize int
+	for _, obj := range objects {
+		for _, symbol := range obj.Symbols {
+			symbols = append(symbols, Symbol{Name: symbol})
+			symbolNamesSize += len(symbol) + 1
+		}
+	}
+	offset := int64(len(GLOBAL_HEADER)) +
+		memberSize(4+4*len(symbols)+symbolNamesSize) +
+		memberSize(4+4*len(objects)+4+2*len(symbols)+symbolNamesSize)
+	if stringTable != nil {
+		offset += memberSize(len(stringTable))
+	}
+	lm := &COFFLinkerMember{Offsets: make([]int64, len(objects))}
+	i := 0
+	for j, obj := range objects {
+		lm.Offsets[j] = offset
+		for range obj.Symbols {
+			symbols[i].Offset = offset
+			i++
+		}
+		offset += memberSize(len(obj.Data))
+	}
+	lm.Symbols = append([]Symbol{}, symbols...)
+	sort.SliceStable(lm.Symbols, func(i, j int) bool { return lm.Symbols[i].Name < lm.Symbols[j].Name })
+
+	aw := NewWriter(w, GNU)
+	if err := aw.WriteSymbolTable(symbols); err != nil {
+		return err
+	}
+	write := func(hdr *Header, data []byte) error {
+		hdr.Size = int64(len(data))
+		if err := aw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		_, err := aw.Write(data)
+		return err
+	}
+	// The second linker member is also named "/", which WriteHeader writes verbatim, as it does the
+	// "//" string table and the "/0" names that refer to it.
+	if err := write(&Header{Name: "/"}, encodeCOFFLinkerMember(lm)); err != nil {
+		return err
+	}
+	if stringTable != nil {
+		if err := write(&Header{Name: "//"}, stringTable); err != nil {
+			return err
+		}
+	}
+	for _, obj := range objects {
+		if err := write(&Header{Name: name, Mode: 0644}, obj.Data); err != nil {
+			return err
+		}
+	}
+	return aw.Close()
+}
+
+// coffImageRelRelocations maps each supported machine type to the type of the relocations that
+// refer to image-relative addresses on that machine.
+var coffImageRelRelocations = map[uint16]uint16{
+	MachineI386:  0x7, // IMAGE_REL_I386_DIR32NB
+	MachineAMD64: 0x3, // IMAGE_REL_AMD64_ADDR32NB
+	MachineARMNT: 0x2, // IMAGE_REL_ARM_ADDR32NB
+	MachineARM64: 0x2, // IMAGE_REL_ARM64_ADDR32NB
+}
+
+// coffNullImportDescriptorName is the name of the symbol that marks the end of the import directory
+// table.
+const coffNullImportDescriptorName = "__NULL_IMPORT_DESCRIPTOR"
+
+// Constants used in COFF object files.
+const (
+	coffFile32BitMachine = 0x100
+
+	coffSectionAlign2Bytes     = 0x200000
+	coffSectionAlign4Bytes     = 0x300000
+	coffSectionAlign8Bytes     = 0x400000
+	coffSectionInitializedData = 0x40
+	coffSectionRead            = 0x40000000
+	coffSectionWrite           = 0x80000000
+
+	coffSymbolExternal = 2
+	coffSymbolStatic   = 3
+	coffSymbolSection  = 104
+
+	// coffImportDirectoryEntrySize is the size of an entry in the import directory table, which
+	// consists of the image-relative addresses of the import lookup table, the time stamp, the
+	// forwarder chain, and the image-relative addresses of the DLL's name and the import address table.
+	coffImportDirectoryEntrySize = 20
+)
+
+// coffFileHeader is the header of a COFF object file.
+type coffFileHeader struct {
+	Machine              uint16
+	NumberOfSections     uint16
+	TimeDateStamp        uint32
+	PointerToSymbolTable uint32
+	NumberOfSymbols      uint32
+	SizeOfOptionalHeader uint16
+	Characteristics      uint16
+}
+
+// coffSection is an entry in the section table of a COFF object file.
+type coffSection struct {
+	Name                 [8]byte
+	VirtualSize          uint32
+	VirtualAddress       uint32
+	SizeOfRawData        uint32
+	PointerToRawData     uint32
+	PointerToRelocations uint32
+	PointerToLinenumbers uint32
+	NumberOfRelocations  uint16
+	NumberOfLinenumbers  uint16
+	Characteristics      uint32
+}
+
+// coffRelocation is an entry in the relocation table of a section in a COFF object file.
+type coffRelocation struct {
+	VirtualAddress   uint32
+	SymbolTableIndex uint32
+	Type             uint16
+}
+
+// coffSymbol is an entry in the symbol table of a COFF object file.
+type coffSymbol struct {
+	Name               [8]byte
+	Value              uint32
+	SectionNumber      int16
+	Type               uint16
+	StorageClass       uint8
+	NumberOfAuxSymbols uint8
+}
+
+// Sizes of the structures in COFF object files.
+const (
+	coffFileHeaderSize = 20
+	coffSectionSize    = 40
+	coffRelocationSize = 10
+)
+
+// coffShortName returns a name that is stored directly in a section or symbol table entry.
+func coffShortName(name string) (b [8]byte) {
+	copy(b[:], name)
+	return b
+}
+
+// coffLongName returns a symbol name that refers to the given offset in the string table.
+func coffLongName(offset int) (b [8]byte) {
+	binary.LittleEndian.PutUint32(b[4:], uint32(offset))
+	return b
+}
+
+// coffObject encodes a COFF object file from the given structures, which are written in order,
+// followed by a string table containing the given strings.
+func coffObject(strs []string, v ...interface{}) []byte {
+	var buf bytes.Buffer
+	for _, x := range v {
+		// Writing to a bytes.Buffer can't fail.
+		binary.Write(&buf, binary.LittleEndian, x)
+	}
+	size := 4
+	for _, str := range strs {
+		size += len(str) + 1
+	}
+	binary.Write(&buf, binary.LittleEndian, uint32(size))
+	for _, str := range strs {
+		buf.WriteString(str)
+		buf.WriteByte(0)
+	}
+	return buf.Bytes()
+}
+
+// coffCharacteristics returns the characteristics of a COFF object file for the given machine type.
+func coffCharacteristics(machine uint16) uint16 {
+	if machine == MachineI386 || machine == MachineARMNT {
+		return coffFile32BitMachine
+	}
+	return 0
+}
+
+// coffImportDescriptor returns a COFF object file that defines the DLL's import descriptor (its entry
+// in the import directory table, in the .idata$2 section) and its name (in the .idata$6 section), and
+// refers to the import lookup table (.idata$4) and import address table (.idata$5) and the symbols
+// that terminate them and the import directory table.
+func coffImportDescriptor(machine, relocation uint16, dll, descriptor, nullThunk string) []byte {
+	const sections, relocations, symbols = 2, 3, 7
+	dllName := append([]byte(dll), 0)
+	idata2 := coffFileHeaderSize + sections*coffSectionSize
+	relocs := idata2 + coffImportDirectoryEntrySize
+	idata6 := relocs + relocations*coffRelocationSize
+	data := uint32(coffSectionInitializedData | coffSectionRead | coffSectionWrite)
+	return coffObject([]string{descriptor, coffNullImportDescriptorName, nullThunk},
+		coffFileHeader{
+			Machine:              machine,
+			NumberOfSections:     sections,
+			PointerToSymbolTable: uint32(idata6 + len(dllName)),
+			NumberOfSymbols:      symbols,
+			Characteristics:      coffCharacteristics(machine),
+		},
+		[sections]coffSection{
+			{
+				Name:                 coffShortName(".idata$2"),
+				SizeOfRawData:        coffImportDirectoryEntrySize,
+				PointerToRawData:     uint32(idata2),
+				PointerToRelocations: uint32(relocs),
+				NumberOfRelocations:  relocations,
+				Characteristics:      coffSectionAlign4Bytes | data,
+			},
+			{
+				Name:             coffShortName(".idata$6"),
+				SizeOfRawData:    uint32(len(dllName)),
+				PointerToRawData: uint32(idata6),
+				Characteristics:  coffSectionAlign2Bytes | data,
+			},
+		},
+		[coffImportDirectoryEntrySize]byte{},
+		// The addresses of the DLL's name, the import lookup table and the import address table.
+		[relocations]coffRelocation{
+			{VirtualAddress: 12, SymbolTableIndex: 2, Type: relocation},
+			{VirtualAddress: 0, SymbolTableIndex: 3, Type: relocation},
+			{VirtualAddress: 16, SymbolTableIndex: 4, Type: relocation},
+		},
+		dllName,
+		[symbols]coffSymbol{
+			{Name: coffLongName(4), SectionNumber: 1, StorageClass: coffSymbolExternal},
+			{Name: coffShortName(".idata$2"), SectionNumber: 1, StorageClass: coffSymbolSection},
+			{Name: coffShortName(".idata$6"), SectionNumber: 2, StorageClass: coffSymbolStatic},
+			{Name: coffShortName(".idata$4"), StorageClass: coffSymbolSection},
+			{Name: coffShortName(".idata$5"), StorageClass: coffSymbolSection},
+			{Name: coffLongName(4 + len(descriptor) + 1), StorageClass: coffSymbolExternal},
+			{Name: coffLongName(4 + len(descriptor) + 1 + len(coffNullImportDescriptorName) + 1), StorageClass: coffSymbolExternal},
+		},
+	)
+}
+
+// coffNullImportDescriptor returns a COFF object file that defines the entry that terminates the
+// import directory table.
+func coffNullImportDescriptor(machine uint16) []byte {
+	idata3 := coffFileHeaderSize + coffSectionSize
+	return coffObject([]string{coffNullImportDescriptorName},
+		coffFileHeader{
+			Machine:              machine,
+			NumberOfSections:     1,
+			PointerToSymbolTable: uint32(idata3 + coffImportDirectoryEntrySize),
+			NumberOfSymbols:      1,
+			Characteristics:      coffCharacteristics(machine),
+		},
+		coffSection{
+			Name:             coffShortName(".idata$3"),
+			SizeOfRawData:    coffImportDirectoryEntrySize,
+			PointerToRawData: uint32(idata3),
+			Characteristics:  coffSectionAlign4Bytes | coffSectionInitializedData | coffSectionRead | coffSectionWrite,
+		},
+		[coffImportDirectoryEntrySize]byte{},
+		coffSymbol{Name: coffLongName(4), SectionNumber: 1, StorageClass: coffSymbolExternal},
+	)
+}
+
+// coffNullThunk returns a COFF object file that defines the entries that terminate the DLL's import
+// lookup table (in the .idata$4 section) and import address table (in the .idata$5 section).
+func coffNullThunk(machine uint16, nullThunk string) []byte {
+	size, align := uint32(8), uint32(coffSectionAlign8Bytes)
+	if coffCharacteristics(machine) == coffFile32BitMachine {
+		size, align = 4, coffSectionAlign4Bytes
+	}
+	idata5 := uint32(coffFileHeaderSize + 2*coffSectionSize)
+	characteristics := align | coffSectionInitializedData | coffSectionRead | coffSectionWrite
+	return coffObject([]string{nullThunk},
+		coffFileHeader{
+			Machine:              machine,
+			NumberOfSections:     2,
+			PointerToSymbolTable: idata5 + 2*size,
+			NumberOfSymbols:      1,
+			Characteristics:      coffCharacteristics(machine),
+		},
+		[2]coffSection{
+			{Name: coffShortName(".idata$5"), SizeOfRawData: size, PointerToRawData: idata5, Characteristics: characteristics},
+			{Name: coffShortName(".idata$4"), SizeOfRawData: size, PointerToRawData: idata5 + size, Characteristics: characteristics},
+		},
+		make([]byte, 2*size),
+		coffSymbol{Name: coffLongName(4), SectionNumber: 1, StorageClass: coffSymbolExternal},
+	)
+}
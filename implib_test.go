@@ -0,0 +1,122 @@
+package ar
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// readImportLibrary returns the headers and data sections of the members of an import library, along
+// with the Reader that read them.
+func readImportLibrary(t *testing.T, r io.Reader) ([]*Header, [][]byte, *Reader) {
+	reader, err := NewReaderWithOptions(r, ReaderOptions{Strict: true})
+	require.NoError(t, err)
+	var hdrs []*Header
+	var data [][]byte
+	for {
+		hdr, err := reader.Next()
+		if err == io.EOF {
+			return hdrs, data, reader
+		}
+		require.NoError(t, err)
+		b, err := io.ReadAll(reader)
+		require.NoError(t, err)
+		hdrs = append(hdrs, hdr)
+		data = append(data, b)
+	}
+}
+
+func TestWriteImportLibrary(t *testing.T) {
+	var buf bytes.Buffer
+	err := WriteImportLibrary(&buf, "a_long_library_name.dll", MachineAMD64, []Export{
+		{Name: "HelloWorld"},
+		{Name: "Goodbye", Ordinal: 5},
+		{Name: "SecretFunction", Ordinal: 7, NoName: true},
+		{Name: "GlobalCounter", Data: true},
+	})
+	require.NoError(t, err)
+
+	// The members' data sections are identical to those in test_data/coff_import.lib, which only
+	// differs in the members' modification times and modes.
+	f, err := os.Open("./test_data/coff_import.lib")
+	require.NoError(t, err)
+	defer f.Close()
+	_, wantData, want := readImportLibrary(t, f)
+	hdrs, data, got := readImportLibrary(t, &buf)
+	assert.Equal(t, wantData, data)
+	for _, hdr := range hdrs {
+		assert.Equal(t, "a_long_library_name.dll", hdr.Name)
+		assert.Equal(t, Epoch, hdr.ModTime)
+	}
+	assert.Equal(t, want.Symbols(), got.Symbols())
+	assert.Equal(t, want.COFFLinkerMember(), got.COFFLinkerMember())
+}
+
+func TestWriteImportLibraryI386(t *testing.T) {
+	var buf bytes.Buffer
+	err := WriteImportLibrary(&buf, "short.dll", MachineI386, []Export{
+		{Name: "_HelloWorld", Ordinal: 1},
+		{Name: "_Goodbye@4"},
+		{Name: "Secret", Ordinal: 3, NoName: true},
+		{Name: "_Pi", Constant: true},
+	})
+	require.NoError(t, err)
+	hdrs, data, reader := readImportLibrary(t, &buf)
+	require.Len(t, hdrs, 7)
+	var objects []*ImportObject
+	for i, hdr := range hdrs {
+		assert.Equal(t, "short.dll", hdr.Name)
+		if IsImportObject(data[i]) {
+			obj, err := ParseImportObject(data[i])
+			require.NoError(t, err)
+			objects = append(objects, obj)
+		}
+	}
+	assert.Equal(t, []*ImportObject{
+		{Machine: MachineI386, Type: ImportCode, NameType: ImportNameNoPrefix, Hint: 1, Symbol: "_HelloWorld", DLL: "short.dll"},
+		{Machine: MachineI386, Type: ImportCode, NameType: ImportName, Symbol: "_Goodbye@4", DLL: "short.dll"},
+		{Machine: MachineI386, Type: ImportCode, NameType: ImportOrdinal, Ordinal: 3, Symbol: "Secret", DLL: "short.dll"},
+		{Machine: MachineI386, Type: ImportConst, NameType: ImportNameNoPrefix, Symbol: "_Pi", DLL: "short.dll"},
+	}, objects)
+
+	var names []string
+	for _, symbol := range reader.COFFLinkerMember().Symbols {
+		names = append(names, symbol.Name)
+	}
+	assert.Equal(t, []string{
+		"Secret",
+		"_Goodbye@4",
+		"_HelloWorld",
+		"_Pi",
+		"__IMPORT_DESCRIPTOR_short",
+		"__NULL_IMPORT_DESCRIPTOR",
+		"__imp_Secret",
+		"__imp__Goodbye@4",
+		"__imp__HelloWorld",
+		"__imp__Pi",
+		"\x7fshort_NULL_THUNK_DATA",
+	}, names)
+}
+
+func TestWriteImportLibraryErrors(t *testing.T) {
+	for _, tc := range []struct {
+		Description string
+		DLL         string
+		Machine     uint16
+		Exports     []Export
+	}{
+		{"Unsupported machine type", "a.dll", 0x5064, nil},
+		{"Empty DLL name", "", MachineAMD64, nil},
+		{"Empty export name", "a.dll", MachineAMD64, []Export{{}}},
+		{"Data and constant export", "a.dll", MachineAMD64, []Export{{Name: "a", Data: true, Constant: true}}},
+		{"Missing ordinal", "a.dll", MachineAMD64, []Export{{Name: "a", NoName: true}}},
+	} {
+		t.Run(tc.Description, func(t *testing.T) {
+			assert.Error(t, WriteImportLibrary(io.Discard, tc.DLL, tc.Machine, tc.Exports))
+		})
+	}
+}
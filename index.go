@@ -0,0 +1,218 @@
+package ar
+
+import (
+	"bytes"
+	"debug/elf"
+	"debug/macho"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+)
+
+// Magic numbers identifying Mach-O object files, in both byte orders.
+var machoMagics = []uint32{macho.Magic32, macho.Magic64}
+
+// objectSymbols returns the names of the global symbols defined by the object file in r, in the
+// order in which they appear in the object file's symbol table. ELF, Mach-O and COFF object files
+// and short import objects are supported; if r contains any other kind of file, objectSymbols
+// returns no symbols and no error.
+func objectSymbols(r io.ReaderAt) ([]string, error) {
+	var magic [4]byte
+	if _, err := r.ReadAt(magic[:], 0); err != nil {
+		// Files shorter than 4 bytes can't be object files.
+		if errors.Is(err, io.EOF) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	if bytes.Equal(magic[:], []byte(elf.ELFMAG)) {
+		return elfSymbols(r)
+	}
+	for _, m := range machoMagics {
+		if binary.BigEndian.Uint32(magic[:]) == m || binary.LittleEndian.Uint32(magic[:]) == m {
+			return machoSymbols(r)
+		}
+	}
+	if IsImportObject(magic[:]) {
+		return importObjectSymbols(r)
+	}
+	if _, ok := coffImageRelRelocations[binary.LittleEndian.Uint16(magic[:])]; ok {
+		return coffSymbols(r)
+	}
+	return nil, nil
+}
+
+// elfSTBGNUUnique is the symbol binding for GNU unique symbols, which debug/elf doesn't define.
+const elfSTBGNUUnique elf.SymBind = 10
+
+// elfSymbols returns the names of the global symbols defined by the ELF object file in r. Like GNU
+// ar, it considers global, weak and unique symbols that are defined in (or common to) any section
+// to be global symbols.
+func elfSymbols(r io.ReaderAt) ([]string, error) {
+	f, err := elf.NewFile(r)
+	if err != nil {
+		return nil, fmt.Errorf("ELF object file: %w", err)
+	}
+	syms, err := f.Symbols()
+	if errors.Is(err, elf.ErrNoSymbols) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("ELF object file: %w", err)
+	}
+	var names []string
+	for _, sym := range syms {
+		switch elf.ST_BIND(sym.Info) {
+		case elf.STB_GLOBAL, elf.STB_WEAK, elfSTBGNUUnique:
+		default:
+			continue
+		}
+		if sym.Section == elf.SHN_UNDEF || sym.Name == "" {
+			continue
+		}
+		names = append(names, sym.Name)
+	}
+	return names, nil
+}
+
+// Mach-O symbol type bits, from <mach-o/nlist.h>.
+const (
+	machoNStab = 0xe0
+	machoNType = 0x0e
+	machoNExt  = 0x01
+	machoNUndf = 0x00
+)
+
+// machoSymbols returns the names of the global symbols defined by the Mach-O object file in r. Like
+// Apple's ranlib, it considers external symbols that are either defined or common (i.e. undefined
+// but with a non-zero value) to be global symbols, and ignores debugging symbols.
+func machoSymbols(r io.ReaderAt) ([]string, error) {
+	f, err := macho.NewFile(r)
+	if err != nil {
+		return nil, fmt.Errorf("Mach-O object file: %w", err)
+	}
+	if f.Symtab == nil {
+		return nil, nil
+	}
+	var names []string
+	for _, sym := range f.Symtab.Syms {
+		if sym.Type&machoNStab != 0 || sym.Type&machoNExt == 0 {
+			continue
+		}
+		if sym.Type&machoNType == machoNUndf && sym.Value == 0 {
+			continue
+		}
+		names = append(names, sym.Name)
+	}
+	return names, nil
+}
+
+// importObjectSymbols returns the names of the symbols defined by the short import object in r.
+// Other kinds of files whose headers resemble short import objects' (e.g. COFF object files with
+// more than 65,535 sections, which Microsoft's tools call "bigobj" files) are ignored.
+func importObjectSymbols(r io.ReaderAt) ([]string, error) {
+	hdr := make([]byte, importObjectHeaderSize)
+	if _, err := r.ReadAt(hdr, 0); err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	if version := binary.LittleEndian.Uint16(hdr[4:]); version != 0 {
+		return nil, nil
+	}
+	// The size of the data that follows the header is untrusted, so check that the object fits in r
+	// before allocating a buffer for it.
+	size, err := readerAtSize(r)
+	if err != nil {
+		return nil, err
+	}
+	n := importObjectHeaderSize + int64(binary.LittleEndian.Uint32(hdr[12:]))
+	if n > size {
+		return nil, fmt.Errorf("short import object: size %d exceeds member size %d", n, size)
+	}
+	data := make([]byte, n)
+	if _, err := r.ReadAt(data, 0); err != nil {
+		return nil, fmt.Errorf("short import object: %w", err)
+	}
+	obj, err := ParseImportObject(data)
+	if err != nil {
+		return nil, err
+	}
+	return obj.symbols(), nil
+}
+
+// COFF symbol section numbers and storage classes, in addition to those used when writing import
+// libraries.
+const (
+	coffSymbolUndefined = 0
+	coffSymbolDebug     = -2
+
+	coffSymbolWeakExternal = 105
+)
+
+// coffSymbolSize is the size of a symbol record (or an auxiliary symbol record) in a COFF object file.
+const coffSymbolSize = 18
+
+// coffSymbols returns the names of the global symbols defined by the COFF object file in r. Like
+// llvm-ar, it considers weak external symbols and external symbols that are either defined, absolute
+// or common (i.e. undefined but with a non-zero value) to be global symbols.
+func coffSymbols(r io.ReaderAt) ([]string, error) {
+	var hdr coffFileHeader
+	if err := binary.Read(io.NewSectionReader(r, 0, coffFileHeaderSize), binary.LittleEndian, &hdr); err != nil {
+		return nil, fmt.Errorf("COFF object file: %w", err)
+	}
+	// Images (i.e. executables and DLLs) have an optional header; object files don't.
+	if hdr.SizeOfOptionalHeader != 0 || hdr.PointerToSymbolTable == 0 {
+		return nil, nil
+	}
+	// The symbol table is immediately followed by the string table, which begins with its own size.
+	symtab := io.NewSectionReader(r, int64(hdr.PointerToSymbolTable), math.MaxInt64-int64(hdr.PointerToSymbolTable))
+	var syms []coffSymbol
+	for i := uint32(0); i < hdr.NumberOfSymbols; i++ {
+		var sym coffSymbol
+		if err := binary.Read(symtab, binary.LittleEndian, &sym); err != nil {
+			return nil, fmt.Errorf("COFF object file: symbol table: %w", err)
+		}
+		syms = append(syms, sym)
+		// Auxiliary symbol records are the same size as symbol records.
+		if _, err := symtab.Seek(int64(sym.NumberOfAuxSymbols)*coffSymbolSize, io.SeekCurrent); err != nil {
+			return nil, err
+		}
+		i += uint32(sym.NumberOfAuxSymbols)
+	}
+	var strtab []byte
+	var size uint32
+	if err := binary.Read(symtab, binary.LittleEndian, &size); err == nil && size > 4 {
+		if strtab, err = io.ReadAll(io.LimitReader(symtab, int64(size-4))); err != nil {
+			return nil, fmt.Errorf("COFF object file: string table: %w", err)
+		}
+	}
+	var names []string
+	for _, sym := range syms {
+		switch {
+		case sym.StorageClass == coffSymbolWeakExternal:
+		case sym.StorageClass != coffSymbolExternal:
+			continue
+		case (sym.SectionNumber == coffSymbolUndefined && sym.Value == 0) || sym.SectionNumber == coffSymbolDebug:
+			continue
+		}
+		// Names of more than 8 bytes are stored in the string table; the symbol record contains four
+		// zero bytes followed by the name's offset in the string table, which includes its size.
+		name := string(bytes.TrimRight(sym.Name[:], "\x00"))
+		if binary.LittleEndian.Uint32(sym.Name[:4]) == 0 {
+			offset := int(binary.LittleEndian.Uint32(sym.Name[4:])) - 4
+			if offset < 0 || offset >= len(strtab) {
+				return nil, fmt.Errorf("COFF object file: invalid string table offset %d", offset+4)
+			}
+			end := bytes.IndexByte(strtab[offset:], 0)
+			if end == -1 {
+				return nil, errors.New("COFF object file: unterminated symbol name")
+			}
+			name = string(strtab[offset : offset+end])
+		}
+		names = append(names, name)
+	}
+	return names, nil
+}
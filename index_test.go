@@ -0,0 +1,113 @@
+package ar
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type machoTestSymbol struct {
+	Name  string
+	Type  uint8
+	Value uint64
+}
+
+// machoObject returns a minimal 64-bit little-endian Mach-O object file containing only a symbol
+// table with the given symbols.
+func machoObject(syms []machoTestSymbol) []byte {
+	const headerSize, symtabCmdSize, nlistSize = 32, 24, 16
+	strtab := []byte{0}
+	var nlists bytes.Buffer
+	for _, sym := range syms {
+		binary.Write(&nlists, binary.LittleEndian, uint32(len(strtab)))
+		nlists.Write([]byte{sym.Type, 1})
+		binary.Write(&nlists, binary.LittleEndian, uint16(0))
+		binary.Write(&nlists, binary.LittleEndian, sym.Value)
+		strtab = append(strtab, sym.Name...)
+		strtab = append(strtab, 0)
+	}
+	symoff := uint32(headerSize + symtabCmdSize)
+	stroff := symoff + uint32(len(syms)*nlistSize)
+	var buf bytes.Buffer
+	for _, x := range []uint32{0xfeedfacf, 0x01000007, 3, 1, 1, symtabCmdSize, 0, 0} {
+		binary.Write(&buf, binary.LittleEndian, x)
+	}
+	for _, x := range []uint32{0x2, symtabCmdSize, symoff, uint32(len(syms)), stroff, uint32(len(strtab))} {
+		binary.Write(&buf, binary.LittleEndian, x)
+	}
+	buf.Write(nlists.Bytes())
+	buf.Write(strtab)
+	return buf.Bytes()
+}
+
+func TestObjectSymbolsELF(t *testing.T) {
+	hdrs, data := readMembers(t, "./test_data/symbols_gnu.a")
+	require.Len(t, hdrs, 2)
+	syms, err := objectSymbols(bytes.NewReader(data[0]))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"add", "counter"}, syms)
+	syms, err = objectSymbols(bytes.NewReader(data[1]))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"sub"}, syms)
+}
+
+func TestObjectSymbolsMachO(t *testing.T) {
+	obj := machoObject([]machoTestSymbol{
+		{Name: "_defined", Type: 0x0f},
+		{Name: "_undefined", Type: 0x01},
+		{Name: "_common", Type: 0x01, Value: 16},
+		{Name: "_private", Type: 0x0e},
+		{Name: "_stab", Type: 0x24},
+	})
+	syms, err := objectSymbols(bytes.NewReader(obj))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"_defined", "_common"}, syms)
+}
+
+func TestObjectSymbolsOtherFiles(t *testing.T) {
+	for _, data := range []string{"", "ab", "Hello world!\n"} {
+		syms, err := objectSymbols(bytes.NewReader([]byte(data)))
+		assert.NoError(t, err)
+		assert.Empty(t, syms)
+	}
+	_, err := objectSymbols(bytes.NewReader([]byte("\x7fELF but not really")))
+	assert.Error(t, err)
+}
+
+func TestObjectSymbolsCOFF(t *testing.T) {
+	archive := openArchive(t, "./test_data/coff_import.lib")
+	var symbols []Symbol
+	for _, f := range archive.Files {
+		r, err := f.Open()
+		require.NoError(t, err)
+		names, err := objectSymbols(r)
+		require.NoError(t, err)
+		for _, name := range names {
+			symbols = append(symbols, Symbol{Name: name, Offset: f.HeaderOffset()})
+		}
+	}
+	assert.Equal(t, archive.Symbols(), symbols)
+}
+
+func TestObjectSymbolsCOFFObject(t *testing.T) {
+	// test_data/coff.obj was compiled for x86-64 Windows by LLVM. It defines a function, a weak
+	// external function, a variable and a common variable, and refers to an undefined function.
+	data, err := os.ReadFile("./test_data/coff.obj")
+	require.NoError(t, err)
+	syms, err := objectSymbols(bytes.NewReader(data))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a_function_with_a_long_name", "weak_fn", ".weak.weak_fn.default.a_function_with_a_long_name", "counter", "common_var"}, syms)
+}
+
+func TestObjectSymbolsTruncatedImportObject(t *testing.T) {
+	// The short import object's header claims that almost 4 GiB of data follow it, which shouldn't be
+	// allocated, since the object is much smaller than that.
+	obj := []byte{0, 0, 0xff, 0xff, 0, 0, 0x64, 0x86, 0, 0, 0, 0, 0xf0, 0xff, 0xff, 0xff, 0, 0, 0, 0}
+	_, err := objectSymbols(io.NewSectionReader(bytes.NewReader(obj), 0, int64(len(obj))))
+	assert.EqualError(t, err, "short import object: size 4294967300 exceeds member size 20")
+}
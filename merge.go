@@ -0,0 +1,97 @@
+package ar
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+)
+
+// DuplicatePolicy determines how Merge handles archive members with the same names.
+type DuplicatePolicy int
+
+const (
+	// KeepAll keeps all members, even if they have the same names as other members.
+	KeepAll DuplicatePolicy = iota
+
+	// KeepFirst keeps only the first member with each name.
+	KeepFirst
+
+	// KeepLast keeps only the last member with each name, which takes the place of the first member
+	// with that name (like GNU ar's "r" command).
+	KeepLast
+
+	// RenameDuplicates keeps all members, but adds a prefix to the names of members that have the same
+	// names as earlier members (see MergeOptions.Prefixes).
+	RenameDuplicates
+)
+
+// MergeOptions specifies optional behaviour for Merge.
+type MergeOptions struct {
+	// WriterOptions specifies how the merged archive is written. Setting SymbolIndex causes a symbol
+	// table to be generated for the merged archive; the input archives' symbol tables are discarded
+	// regardless.
+	WriterOptions
+
+	// Duplicates determines how members with the same names are handled.
+	Duplicates DuplicatePolicy
+
+	// Prefixes are the prefixes added to the names of duplicate members if Duplicates is
+	// RenameDuplicates: Prefixes[i] is added to the names of duplicate members from the i-th archive.
+	// If a renamed member's name is still a duplicate, the prefix is added repeatedly until it isn't.
+	// If Prefixes is nil, the prefix for the i-th archive is the decimal representation of i followed
+	// by "_".
+	Prefixes []string
+}
+
+// Merge writes to w an archive of the given variant containing the members of each of the given
+// archives, in order. The data sections of the members are copied directly from the input archives;
+// the string table and symbol table (if any) are generated once for the merged archive. Thin archives
+// can't be merged, since their members' data sections are stored in external files.
+func Merge(w io.Writer, variant Variant, archives []*Archive, opts MergeOptions) error {
+	if opts.Duplicates == RenameDuplicates && opts.Prefixes != nil && len(opts.Prefixes) != len(archives) {
+		return fmt.Errorf("ar: %d prefixes given for %d archives", len(opts.Prefixes), len(archives))
+	}
+	var members []member
+	// byName maps the names of members to their indices in members.
+	byName := map[string]int{}
+	for i, a := range archives {
+		if a.Thin() {
+			return fmt.Errorf("ar: can't merge thin archive %d", i)
+		}
+		for _, f := range a.Files {
+			m := member{hdr: f.Header}
+			m.data, _ = f.Open()
+			j, duplicate := byName[m.hdr.Name]
+			switch {
+			case !duplicate || opts.Duplicates == KeepAll:
+			case opts.Duplicates == KeepFirst:
+				continue
+			case opts.Duplicates == KeepLast:
+				members[j] = m
+				continue
+			case opts.Duplicates == RenameDuplicates:
+				prefix := strconv.Itoa(i) + "_"
+				if opts.Prefixes != nil {
+					prefix = opts.Prefixes[i]
+				}
+				if prefix == "" {
+					return &ErrFileName{Name: m.hdr.Name, Offset: f.headerOffset, Index: f.index, Err: fmt.Errorf("duplicate member in archive %d has empty prefix", i)}
+				}
+				for duplicate {
+					m.hdr.Name = prefix + m.hdr.Name
+					_, duplicate = byName[m.hdr.Name]
+				}
+			}
+			if _, present := byName[m.hdr.Name]; !present {
+				byName[m.hdr.Name] = len(members)
+			}
+			members = append(members, m)
+		}
+	}
+	opts.Thin = false
+	out := newWriter(w, variant, opts.WriterOptions)
+	if err := out.writeArchive(members, nil); err != nil {
+		return err
+	}
+	return out.Close()
+}
@@ -0,0 +1,122 @@
+package ar
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testArchive returns an Archive of the given variant containing members with the given names, each
+// containing its own name followed by the given suffix.
+func testArchive(t *testing.T, variant Variant, suffix string, names ...string) *Archive {
+	var buf bytes.Buffer
+	writer := NewWriterWithOptions(&buf, variant, WriterOptions{Buffer: true})
+	for _, name := range names {
+		data := name + suffix
+		require.NoError(t, writer.WriteHeader(&Header{Name: name, Mode: 0644, Size: int64(len(data))}))
+		_, err := writer.Write([]byte(data))
+		require.NoError(t, err)
+	}
+	require.NoError(t, writer.Close())
+	archive, err := NewArchive(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	require.NoError(t, err)
+	return archive
+}
+
+func TestMerge(t *testing.T) {
+	for _, tc := range []struct {
+		Description string
+		Options     MergeOptions
+		Expected    []string
+	}{
+		{
+			Description: "Keep all",
+			Options:     MergeOptions{Duplicates: KeepAll},
+			Expected:    []string{"a.o:0", "a_long_member_name.o:0", "b.o:1", "a.o:1", "a.o:2", "a_long_member_name.o:2"},
+		},
+		{
+			Description: "Keep first",
+			Options:     MergeOptions{Duplicates: KeepFirst},
+			Expected:    []string{"a.o:0", "a_long_member_name.o:0", "b.o:1"},
+		},
+		{
+			Description: "Keep last",
+			Options:     MergeOptions{Duplicates: KeepLast},
+			Expected:    []string{"a.o:2", "a_long_member_name.o:2", "b.o:1"},
+		},
+		{
+			Description: "Rename duplicates",
+			Options:     MergeOptions{Duplicates: RenameDuplicates},
+			Expected:    []string{"a.o:0", "a_long_member_name.o:0", "b.o:1", "1_a.o:1", "2_a.o:2", "2_a_long_member_name.o:2"},
+		},
+		{
+			Description: "Rename duplicates with prefixes",
+			Options:     MergeOptions{Duplicates: RenameDuplicates, Prefixes: []string{"x_", "y_", "y_"}},
+			Expected:    []string{"a.o:0", "a_long_member_name.o:0", "b.o:1", "y_a.o:1", "y_y_a.o:2", "y_a_long_member_name.o:2"},
+		},
+	} {
+		for _, v := range []struct {
+			Name    string
+			Variant Variant
+		}{{"GNU", GNU}, {"BSD", BSD}} {
+			variant := v.Variant
+			t.Run(tc.Description+" "+v.Name, func(t *testing.T) {
+				archives := []*Archive{
+					testArchive(t, BSD, ":0", "a.o", "a_long_member_name.o"),
+					testArchive(t, GNU, ":1", "b.o", "a.o"),
+					testArchive(t, BSD, ":2", "a.o", "a_long_member_name.o"),
+				}
+				var buf bytes.Buffer
+				require.NoError(t, Merge(&buf, variant, archives, tc.Options))
+				merged, err := NewArchive(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+				require.NoError(t, err)
+				assert.Equal(t, variant, merged.Variant())
+				var actual []string
+				for _, f := range merged.Files {
+					r, err := f.Open()
+					require.NoError(t, err)
+					data, err := io.ReadAll(r)
+					require.NoError(t, err)
+					actual = append(actual, f.Name+string(data[len(data)-2:]))
+				}
+				assert.Equal(t, tc.Expected, actual)
+			})
+		}
+	}
+}
+
+func TestMergeSymbolIndex(t *testing.T) {
+	archives := []*Archive{
+		openArchive(t, "./test_data/symbols_gnu.a"),
+		openArchive(t, "./test_data/symbols_bsd.a"),
+	}
+	var buf bytes.Buffer
+	require.NoError(t, Merge(&buf, GNU, archives, MergeOptions{
+		WriterOptions: WriterOptions{SymbolIndex: true},
+		Duplicates:    RenameDuplicates,
+		Prefixes:      []string{"gnu_", "bsd_"},
+	}))
+	merged, err := NewArchive(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	require.NoError(t, err)
+	require.Len(t, merged.Files, 4)
+	assert.Equal(t, "bsd_add.o", merged.Files[2].Name)
+	table := merged.SymbolTable()
+	assert.Equal(t, "add.o", table["add"].Name)
+	assert.Equal(t, "sub.o", table["sub"].Name)
+	assert.Len(t, merged.Symbols(), 6)
+}
+
+func TestMergeErrors(t *testing.T) {
+	var buf bytes.Buffer
+	archives := []*Archive{openArchive(t, "./test_data/thin.a")}
+	assert.Error(t, Merge(&buf, GNU, archives, MergeOptions{}))
+
+	archives = []*Archive{openArchive(t, "./test_data/hello.a"), openArchive(t, "./test_data/hello.a")}
+	assert.Error(t, Merge(&buf, GNU, archives, MergeOptions{Duplicates: RenameDuplicates, Prefixes: []string{"a_"}}))
+	var nameErr *ErrFileName
+	require.ErrorAs(t, Merge(&buf, GNU, archives, MergeOptions{Duplicates: RenameDuplicates, Prefixes: []string{"a_", ""}}), &nameErr)
+	assert.Equal(t, "hello.txt", nameErr.Name)
+}
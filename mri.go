@@ -0,0 +1,407 @@
+package ar
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// MRICommand is a command in an MRI script.
+type MRICommand struct {
+	// Line is the line number in the script on which the command begins.
+	Line int
+
+	// Name is the name of the command, in upper case (e.g. "ADDLIB").
+	Name string
+
+	// Args are the command's arguments.
+	Args []string
+
+	// Modules are the names of the archive members listed in parentheses after an ADDLIB command's
+	// arguments, if any.
+	Modules []string
+}
+
+// MRIScript is a script written in the MRI librarian's command language, which GNU ar accepts in its
+// "-M" mode. It is commonly used by build systems to combine static libraries.
+//
+// The following commands are supported (command names are case-insensitive):
+//
+//	CREATE archive               begin a new archive, which is written to the given path by SAVE
+//	OPEN archive                 begin editing the existing archive at the given path
+//	ADDLIB archive [(member...)] add all members (or the named members) of the given archive
+//	ADDMOD file...               add the given files as members
+//	DELETE member...             delete the named members
+//	REPLACE file...              replace the members with the same names as the given files
+//	CLEAR                        discard all changes made since the last CREATE or OPEN
+//	SAVE                         write the current archive, and stop editing it
+//	END                          end the script, discarding any unsaved changes
+//
+// Arguments are separated by spaces or commas. Text following a "*" or ";" is a comment, and a line
+// ending with "+" is continued on the next line.
+type MRIScript struct {
+	Commands []MRICommand
+}
+
+// MRIOptions specifies optional behaviour for MRIScript.Run.
+type MRIOptions struct {
+	// WriterOptions specifies how archives are written by the SAVE command. Thin archives are not
+	// supported, so Thin is ignored. If an archive opened with the OPEN command has a symbol table,
+	// SymbolIndex is set automatically when it is saved, so that the symbol table is regenerated.
+	WriterOptions
+
+	// Variant is the variant of the ar file format used for archives begun with CREATE.
+	Variant Variant
+
+	// Dir is the directory relative to which file paths in the script are resolved. If it is empty,
+	// they are resolved relative to the current working directory.
+	Dir string
+}
+
+// mriArgs is the number of arguments that each MRI command accepts, or -1 if it accepts one or more
+// arguments.
+var mriArgs = map[string]int{
+	"CREATE":  1,
+	"OPEN":    1,
+	"ADDLIB":  1,
+	"ADDMOD":  -1,
+	"DELETE":  -1,
+	"REPLACE": -1,
+	"CLEAR":   0,
+	"SAVE":    0,
+	"END":     0,
+}
+
+// ParseMRIScript parses an MRI script read from r. It returns an *ErrMRIScript if the script is
+// malformed or contains unsupported commands.
+func ParseMRIScript(r io.Reader) (*MRIScript, error) {
+	script := &MRIScript{}
+	scanner := bufio.NewScanner(r)
+	var text string
+	line, start := 0, 0
+	for scanner.Scan() {
+		line++
+		if text == "" {
+			start = line
+		}
+		s := scanner.Text()
+		if i := strings.IndexAny(s, "*;"); i >= 0 {
+			s = s[:i]
+		}
+		s = strings.TrimSpace(s)
+		if strings.HasSuffix(s, "+") {
+			text += strings.TrimSuffix(s, "+") + " "
+			continue
+		}
+		text += s
+		if strings.TrimSpace(text) != "" {
+			cmd, err := parseMRICommand(start, text)
+			if err != nil {
+				return nil, err
+			}
+			script.Commands = append(script.Commands, cmd)
+		}
+		text = ""
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("ar: read MRI script: %w", err)
+	}
+	if strings.TrimSpace(text) != "" {
+		return nil, &ErrMRIScript{Line: start, Err: errors.New("unterminated line continuation")}
+	}
+	return script, nil
+}
+
+// parseMRICommand parses the text of an MRI command that begins on the given line.
+func parseMRICommand(line int, text string) (MRICommand, error) {
+	cmd := MRICommand{Line: line}
+	inModules := false
+	words := strings.FieldsFunc(strings.NewReplacer("(", " ( ", ")", " ) ").Replace(text), func(r rune) bool {
+		return r == ' ' || r == '\t' || r == ','
+	})
+	for i, word := range words {
+		switch {
+		case i == 0:
+			cmd.Name = strings.ToUpper(word)
+		case word == "(":
+			if cmd.Name != "ADDLIB" || inModules || cmd.Modules != nil {
+				return cmd, &ErrMRIScript{Line: line, Err: errors.New("unexpected '('")}
+			}
+			inModules = true
+			cmd.Modules = []string{}
+		case word == ")":
+			if !inModules || i != len(words)-1 {
+				return cmd, &ErrMRIScript{Line: line, Err: errors.New("unexpected ')'")}
+			}
+			inModules = false
+		case inModules:
+			cmd.Modules = append(cmd.Modules, word)
+		default:
+			cmd.Args = append(cmd.Args, word)
+		}
+	}
+	if inModules {
+		return cmd, &ErrMRIScript{Line: line, Err: errors.New("missing ')'")}
+	}
+	n, ok := mriArgs[cmd.Name]
+	switch {
+	case !ok:
+		return cmd, &ErrMRIScript{Line: line, Err: fmt.Errorf("unsupported command %s", cmd.Name)}
+	case n >= 0 && len(cmd.Args) != n:
+		return cmd, &ErrMRIScript{Line: line, Err: fmt.Errorf("%s takes %d argument(s)", cmd.Name, n)}
+	case n < 0 && len(cmd.Args) == 0:
+		return cmd, &ErrMRIScript{Line: line, Err: fmt.Errorf("%s takes at least one argument", cmd.Name)}
+	}
+	return cmd, nil
+}
+
+// mriState is the state of an MRI script that is being run.
+type mriState struct {
+	opts MRIOptions
+
+	// path is the path to the current archive, or "" if there is no current archive.
+	path string
+
+	// variant is the variant of the ar file format used by the current archive.
+	variant Variant
+
+	// symbolIndex is true if the current archive had a symbol table when it was opened, in which case
+	// the symbol table is regenerated when it is saved (as with Edit and NewAppender), regardless of the
+	// SymbolIndex option.
+	symbolIndex bool
+
+	// coff is true if the current archive had a second linker member when it was opened, in which case
+	// it is written in the format produced by Microsoft's tools when it is saved.
+	coff bool
+
+	// original is the list of members that the current archive contained when it was opened.
+	original []member
+
+	// members is the list of members of the current archive.
+	members []member
+
+	// files are the files that have been opened while running the script, which are closed when the
+	// script ends.
+	files []*os.File
+}
+
+// Run runs the script. It stops at the first command that fails, returning an *ErrMRIScript.
+func (s *MRIScript) Run(opts MRIOptions) error {
+	state := &mriState{opts: opts}
+	defer state.close()
+	for _, cmd := range s.Commands {
+		if cmd.Name == "END" {
+			break
+		}
+		if err := state.run(cmd); err != nil {
+			return &ErrMRIScript{Line: cmd.Line, Err: err}
+		}
+	}
+	return nil
+}
+
+// run runs a single command.
+func (st *mriState) run(cmd MRICommand) error {
+	if cmd.Name != "CREATE" && cmd.Name != "OPEN" && st.path == "" {
+		return errors.New("no archive is open")
+	}
+	switch cmd.Name {
+	case "CREATE":
+		st.path, st.variant, st.symbolIndex, st.coff = cmd.Args[0], st.opts.Variant, false, false
+		st.original, st.members = nil, nil
+	case "OPEN":
+		a, err := st.openArchive(cmd.Args[0])
+		if err != nil {
+			return err
+		}
+		members, err := archiveMembers(a, false, nil, nil)
+		if err != nil {
+			return err
+		}
+		st.path, st.variant, st.symbolIndex, st.coff = cmd.Args[0], a.Variant(), a.Symbols() != nil, a.COFFLinkerMember() != nil
+		st.original, st.members = members, append([]member{}, members...)
+	case "ADDLIB":
+		return st.addLib(cmd.Args[0], cmd.Modules)
+	case "ADDMOD":
+		for _, path := range cmd.Args {
+			m, err := st.openMember(path)
+			if err != nil {
+				return err
+			}
+			st.members = append(st.members, m)
+		}
+	case "DELETE":
+		for _, name := range cmd.Args {
+			i := st.find(name)
+			if i < 0 {
+				return fmt.Errorf("no member named '%s'", name)
+			}
+			st.members = append(st.members[:i], st.members[i+1:]...)
+		}
+	case "REPLACE":
+		for _, path := range cmd.Args {
+			i := st.find(filepath.Base(path))
+			if i < 0 {
+				return fmt.Errorf("no member named '%s'", filepath.Base(path))
+			}
+			m, err := st.openMember(path)
+			if err != nil {
+				return err
+			}
+			st.members[i] = m
+		}
+	case "CLEAR":
+		st.members = append([]member{}, st.original...)
+	case "SAVE":
+		if err := st.save(); err != nil {
+			return err
+		}
+		st.path = ""
+	}
+	return nil
+}
+
+// resolve resolves a file path given in the script.
+func (st *mriState) resolve(path string) string {
+	if st.opts.Dir == "" || filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(st.opts.Dir, path)
+}
+
+// open opens the file at the given path for reading, keeping it open until the script ends.
+func (st *mriState) open(path string) (*os.File, int64, error) {
+	f, err := os.Open(st.resolve(path))
+	if err != nil {
+		return nil, 0, err
+	}
+	st.files = append(st.files, f)
+	fi, err := f.Stat()
+	if err != nil {
+		return nil, 0, err
+	}
+	return f, fi.Size(), nil
+}
+
+// openArchive opens the archive at the given path.
+func (st *mriState) openArchive(path string) (*Archive, error) {
+	f, size, err := st.open(path)
+	if err != nil {
+		return nil, err
+	}
+	a, err := NewArchive(f, size)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if a.Thin() {
+		return nil, fmt.Errorf("%s: thin archives are not supported", path)
+	}
+	return a, nil
+}
+
+// openMember opens the file at the given path as an archive member, whose name is the file's base
+// name.
+func (st *mriState) openMember(path string) (member, error) {
+	f, size, err := st.open(path)
+	if err != nil {
+		return member{}, err
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		return member{}, err
+	}
+	hdr, err := FileInfoHeader(fi)
+	if err != nil {
+		return member{}, fmt.Errorf("%s: %w", path, err)
+	}
+	return member{hdr: *hdr, data: io.NewSectionReader(f, 0, size)}, nil
+}
+
+// addLib adds the members of the archive at the given path, or only those with the given names if
+// any are given.
+func (st *mriState) addLib(path string, names []string) error {
+	a, err := st.openArchive(path)
+	if err != nil {
+		return err
+	}
+	members, err := archiveMembers(a, false, nil, nil)
+	if err != nil {
+		return err
+	}
+	if len(names) == 0 {
+		st.members = append(st.members, members...)
+		return nil
+	}
+	for _, name := range names {
+		found := false
+		for _, m := range members {
+			if m.hdr.Name == name {
+				st.members = append(st.members, m)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("%s: no member named '%s'", path, name)
+		}
+	}
+	return nil
+}
+
+// find returns the index of the first member of the current archive with the given name, or -1 if
+// there is no such member.
+func (st *mriState) find(name string) int {
+	for i, m := range st.members {
+		if m.hdr.Name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// save writes the current archive to a temporary file, which is renamed over the archive's path if
+// successful.
+func (st *mriState) save() error {
+	path := st.resolve(st.path)
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".ar-")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	opts := st.opts.WriterOptions
+	opts.Thin = false
+	opts.SymbolIndex = opts.SymbolIndex || st.symbolIndex
+	out := newWriter(tmp, st.variant, opts)
+	out.coff = st.coff
+	if err := out.writeArchive(st.members, nil); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := out.Close(); err != nil {
+		tmp.Close()
+		return err
+	}
+	perm := os.FileMode(0644)
+	if fi, err := os.Stat(path); err == nil {
+		perm = fi.Mode().Perm()
+	}
+	if err := tmp.Chmod(perm); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// close closes the files that have been opened while running the script.
+func (st *mriState) close() {
+	for _, f := range st.files {
+		f.Close()
+	}
+}
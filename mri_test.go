@@ -0,0 +1,143 @@
+package ar
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseMRIScript(t *testing.T) {
+	script, err := ParseMRIScript(strings.NewReader(`* Combine some libraries.
+create out.a
+ADDLIB liba.a ; all of it
+addlib libb.a (b1.o, b2.o)
+ADDMOD c1.o,c2.o +
+  c3.o
+
+SAVE
+END
+`))
+	require.NoError(t, err)
+	assert.Equal(t, []MRICommand{
+		{Line: 2, Name: "CREATE", Args: []string{"out.a"}},
+		{Line: 3, Name: "ADDLIB", Args: []string{"liba.a"}},
+		{Line: 4, Name: "ADDLIB", Args: []string{"libb.a"}, Modules: []string{"b1.o", "b2.o"}},
+		{Line: 5, Name: "ADDMOD", Args: []string{"c1.o", "c2.o", "c3.o"}},
+		{Line: 8, Name: "SAVE"},
+		{Line: 9, Name: "END"},
+	}, script.Commands)
+}
+
+func TestParseMRIScriptErrors(t *testing.T) {
+	for _, tc := range []struct {
+		Description string
+		Script      string
+		Error       string
+	}{
+		{"Unsupported command", "CREATE a.a\nEXTRACT a.o", "ar: MRI script line 2: unsupported command EXTRACT"},
+		{"Too many arguments", "CREATE a.a b.a", "ar: MRI script line 1: CREATE takes 1 argument(s)"},
+		{"Missing arguments", "\nADDMOD", "ar: MRI script line 2: ADDMOD takes at least one argument"},
+		{"Unexpected parenthesis", "ADDMOD (a.o)", "ar: MRI script line 1: unexpected '('"},
+		{"Missing parenthesis", "ADDLIB a.a (a.o", "ar: MRI script line 1: missing ')'"},
+		{"Unterminated continuation", "ADDMOD a.o +", "ar: MRI script line 1: unterminated line continuation"},
+	} {
+		t.Run(tc.Description, func(t *testing.T) {
+			_, err := ParseMRIScript(strings.NewReader(tc.Script))
+			assert.EqualError(t, err, tc.Error)
+		})
+	}
+}
+
+// runMRIScript runs an MRI script in the test_data directory, saving archives to a temporary
+// directory.
+func runMRIScript(t *testing.T, script string) (string, error) {
+	dir := t.TempDir()
+	testData, err := filepath.Abs("test_data")
+	require.NoError(t, err)
+	s, err := ParseMRIScript(strings.NewReader(strings.ReplaceAll(script, "$OUT", dir)))
+	require.NoError(t, err)
+	return dir, s.Run(MRIOptions{
+		WriterOptions: WriterOptions{SymbolIndex: true},
+		Variant:       GNU,
+		Dir:           testData,
+	})
+}
+
+func TestRunMRIScript(t *testing.T) {
+	dir, err := runMRIScript(t, `CREATE $OUT/out.a
+ADDLIB symbols_gnu.a
+ADDLIB long_filenames_gnu.a (20xxxxxxxxxxxxxxxxxx, 1)
+ADDMOD objs/h.txt
+DELETE add.o
+SAVE
+END
+`)
+	require.NoError(t, err)
+	archive := openArchive(t, filepath.Join(dir, "out.a"))
+	var names []string
+	for _, f := range archive.Files {
+		names = append(names, f.Name)
+	}
+	assert.Equal(t, []string{"sub.o", "20xxxxxxxxxxxxxxxxxx", "1", "h.txt"}, names)
+	assert.Equal(t, []Symbol{{Name: "sub", Offset: archive.Files[0].HeaderOffset()}}, archive.Symbols())
+	r, err := archive.Open("h.txt")
+	require.NoError(t, err)
+	fi, err := r.Stat()
+	require.NoError(t, err)
+	assert.Equal(t, int64(6), fi.Size())
+}
+
+func TestRunMRIScriptOpen(t *testing.T) {
+	dir, err := runMRIScript(t, `CREATE $OUT/out.a
+ADDLIB long_filenames_gnu.a
+SAVE
+OPEN $OUT/out.a
+DELETE 1
+CLEAR
+DELETE 2x, 3xx
+REPLACE objs/sub/a_long_object_name.o
+END
+`)
+	assert.EqualError(t, err, "ar: MRI script line 8: no member named 'a_long_object_name.o'")
+	// The archive should be unchanged, since it wasn't saved after being opened.
+	hdrs, _ := readMembers(t, filepath.Join(dir, "out.a"))
+	assert.Len(t, hdrs, 20)
+
+	_, err = runMRIScript(t, "ADDMOD objs/h.txt")
+	assert.EqualError(t, err, "ar: MRI script line 1: no archive is open")
+}
+
+func TestRunMRIScriptOpenIndexedArchive(t *testing.T) {
+	dir := t.TempDir()
+	data, err := os.ReadFile("./test_data/symbols_gnu.a")
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "lib.a"), data, 0644))
+	testData, err := filepath.Abs("test_data")
+	require.NoError(t, err)
+	s, err := ParseMRIScript(strings.NewReader("OPEN " + filepath.Join(dir, "lib.a") + "\nADDMOD objs/h.txt\nSAVE\n"))
+	require.NoError(t, err)
+	// The archive's symbol table should be regenerated, even though SymbolIndex isn't set.
+	require.NoError(t, s.Run(MRIOptions{Dir: testData}))
+	original := openArchive(t, "./test_data/symbols_gnu.a")
+	archive := openArchive(t, filepath.Join(dir, "lib.a"))
+	require.Len(t, archive.Files, 3)
+	require.Len(t, archive.Symbols(), len(original.Symbols()))
+	for name, f := range original.SymbolTable() {
+		assert.Equal(t, f.Name, archive.SymbolTable()[name].Name)
+	}
+}
+
+func TestRunMRIScriptEnd(t *testing.T) {
+	dir, err := runMRIScript(t, `CREATE $OUT/out.a
+ADDMOD objs/h.txt
+END
+SAVE
+`)
+	require.NoError(t, err)
+	_, err = os.Stat(filepath.Join(dir, "out.a"))
+	assert.True(t, os.IsNotExist(err))
+}
@@ -0,0 +1,171 @@
+// Package pack reads and modifies Go package archives, the ar archives produced by the Go compiler
+// (with its -pack flag) and by go tool pack.
+//
+// The first member of a Go package archive is named __.PKGDEF. It begins with a text header, which
+// identifies the toolchain that compiled the package, and contains the package's export data (the
+// description of its exported API that the compiler reads when compiling packages that import it).
+// The remaining members are object files, usually a single _go_.o member produced by the compiler
+// followed by any objects assembled from the package's assembly sources.
+package pack
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/please-build/ar"
+)
+
+// PkgDef is the name of the archive member containing a Go package's export data.
+const PkgDef = "__.PKGDEF"
+
+const (
+	// objectHeaderPrefix is the prefix of the first line of the __.PKGDEF member's text header.
+	objectHeaderPrefix = "go object "
+
+	// exportDataStart and exportDataEnd are the lines that delimit the export data.
+	exportDataStart = "$$B\n"
+	exportDataEnd   = "\n$$\n"
+)
+
+// ErrNotPackage indicates that an archive is not a Go package archive, because its first member is
+// not named __.PKGDEF.
+var ErrNotPackage = errors.New("pack: not a Go package archive")
+
+// Header is the text header at the start of a Go package archive's __.PKGDEF member.
+type Header struct {
+	// ObjectHeader is the first line of the header (without its trailing newline), which identifies
+	// the toolchain that compiled the package, e.g. "go object linux amd64 go1.21.0 X:none".
+	ObjectHeader string
+
+	// GOOS, GOARCH and Version are the target operating system, target architecture and toolchain
+	// version given in ObjectHeader.
+	GOOS, GOARCH, Version string
+
+	// BuildID is the package's build ID, or "" if it was compiled without one.
+	BuildID string
+
+	// Main is true if the package is a main package.
+	Main bool
+}
+
+// Package is a Go package archive.
+type Package struct {
+	// Header is the text header of the archive's __.PKGDEF member.
+	Header Header
+
+	// Archive is the underlying ar archive.
+	Archive *ar.Archive
+
+	// pkgdef is the archive's __.PKGDEF member.
+	pkgdef *ar.File
+
+	// exportOffset and exportSize are the offset and size of the export data within the data section
+	// of the __.PKGDEF member.
+	exportOffset, exportSize int64
+}
+
+// IsPackage returns whether the archive a is a Go package archive.
+func IsPackage(a *ar.Archive) bool {
+	return len(a.Files) > 0 && a.Files[0].Name == PkgDef
+}
+
+// Open parses the __.PKGDEF member of the Go package archive a. It returns ErrNotPackage if a isn't a
+// Go package archive.
+func Open(a *ar.Archive) (*Package, error) {
+	if !IsPackage(a) {
+		return nil, ErrNotPackage
+	}
+	p := &Package{Archive: a, pkgdef: a.Files[0]}
+	r, err := p.pkgdef.Open()
+	if err != nil {
+		return nil, err
+	}
+	br := bufio.NewReader(r)
+	// The header ends with a blank line, which is followed by the line that begins the export data.
+	inHeader := true
+	for {
+		line, err := br.ReadString('\n')
+		if err == io.EOF {
+			return nil, fmt.Errorf("pack: %s: missing export data", PkgDef)
+		} else if err != nil {
+			return nil, err
+		}
+		p.exportOffset += int64(len(line))
+		if line == exportDataStart {
+			break
+		}
+		if !inHeader {
+			continue
+		}
+		line = strings.TrimSuffix(line, "\n")
+		switch {
+		case p.Header.ObjectHeader == "":
+			if !strings.HasPrefix(line, objectHeaderPrefix) {
+				return nil, fmt.Errorf("pack: %s: invalid object header %q", PkgDef, line)
+			}
+			p.Header.ObjectHeader = line
+			if fields := strings.Fields(strings.TrimPrefix(line, objectHeaderPrefix)); len(fields) >= 3 {
+				p.Header.GOOS, p.Header.GOARCH, p.Header.Version = fields[0], fields[1], fields[2]
+			}
+		case line == "":
+			inHeader = false
+		case line == "main":
+			p.Header.Main = true
+		case strings.HasPrefix(line, "build id "):
+			id, err := strconv.Unquote(strings.TrimPrefix(line, "build id "))
+			if err != nil {
+				return nil, fmt.Errorf("pack: %s: invalid build id: %w", PkgDef, err)
+			}
+			p.Header.BuildID = id
+		}
+	}
+	p.exportSize = p.pkgdef.Size - p.exportOffset - int64(len(exportDataEnd))
+	if p.exportSize < 0 {
+		return nil, fmt.Errorf("pack: %s: unterminated export data", PkgDef)
+	}
+	end := make([]byte, len(exportDataEnd))
+	if _, err := r.ReadAt(end, p.exportOffset+p.exportSize); err != nil {
+		return nil, err
+	}
+	if string(end) != exportDataEnd {
+		return nil, fmt.Errorf("pack: %s: unterminated export data", PkgDef)
+	}
+	return p, nil
+}
+
+// ExportData returns a reader for the package's export data. The first byte of the export data
+// identifies its format (e.g. 'u' for the unified export data format used since Go 1.20).
+func (p *Package) ExportData() *io.SectionReader {
+	r, _ := p.pkgdef.Open()
+	return io.NewSectionReader(r, p.exportOffset, p.exportSize)
+}
+
+// Objects returns the archive's object file members, i.e. all of its members except __.PKGDEF.
+func (p *Package) Objects() []*ar.File {
+	return p.Archive.Files[1:]
+}
+
+// ReplaceExportData writes to w a copy of the package archive with its export data replaced by the
+// given data. The text header of the __.PKGDEF member and the object file members are copied
+// unchanged from the original archive.
+func (p *Package) ReplaceExportData(w io.Writer, data []byte) error {
+	r, err := p.pkgdef.Open()
+	if err != nil {
+		return err
+	}
+	var pkgdef bytes.Buffer
+	if _, err := io.Copy(&pkgdef, io.NewSectionReader(r, 0, p.exportOffset)); err != nil {
+		return err
+	}
+	pkgdef.Write(data)
+	pkgdef.WriteString(exportDataEnd)
+	hdr := p.pkgdef.Header
+	hdr.Size = int64(pkgdef.Len())
+	op := ar.ReplaceMember(PkgDef, &hdr, bytes.NewReader(pkgdef.Bytes()))
+	return ar.Edit(w, p.Archive, []ar.EditOp{op}, ar.EditOptions{})
+}
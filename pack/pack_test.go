@@ -0,0 +1,147 @@
+package pack
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"testing"
+
+	"github.com/please-build/ar"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func openArchive(t *testing.T, path string) *ar.Archive {
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	return newArchive(t, data)
+}
+
+func newArchive(t *testing.T, data []byte) *ar.Archive {
+	a, err := ar.NewArchive(bytes.NewReader(data), int64(len(data)))
+	require.NoError(t, err)
+	return a
+}
+
+// memberData returns the data section of the named member of the archive a.
+func memberData(t *testing.T, a *ar.Archive, name string) []byte {
+	for _, f := range a.Files {
+		if f.Name == name {
+			r, err := f.Open()
+			require.NoError(t, err)
+			data, err := io.ReadAll(r)
+			require.NoError(t, err)
+			return data
+		}
+	}
+	require.Failf(t, "missing archive member", "%s", name)
+	return nil
+}
+
+// testPackage returns a Go package archive whose __.PKGDEF member has the given contents.
+func testPackage(t *testing.T, pkgdef string) *ar.Archive {
+	var buf bytes.Buffer
+	writer := ar.NewWriter(&buf, ar.BSD)
+	for _, m := range []struct{ Name, Data string }{{PkgDef, pkgdef}, {"_go_.o", "object"}} {
+		require.NoError(t, writer.WriteHeader(&ar.Header{Name: m.Name, Mode: 0644, Size: int64(len(m.Data))}))
+		_, err := writer.Write([]byte(m.Data))
+		require.NoError(t, err)
+	}
+	require.NoError(t, writer.Close())
+	return newArchive(t, buf.Bytes())
+}
+
+func TestOpen(t *testing.T) {
+	a := openArchive(t, "./test_data/greet.a")
+	require.True(t, IsPackage(a))
+	p, err := Open(a)
+	require.NoError(t, err)
+	assert.Regexp(t, `^go object linux amd64 go1\.\d+`, p.Header.ObjectHeader)
+	assert.Equal(t, "linux", p.Header.GOOS)
+	assert.Equal(t, "amd64", p.Header.GOARCH)
+	assert.Regexp(t, `^go1\.\d+`, p.Header.Version)
+	assert.Equal(t, "abcdef/ghijkl", p.Header.BuildID)
+	assert.False(t, p.Header.Main)
+
+	data, err := io.ReadAll(p.ExportData())
+	require.NoError(t, err)
+	assert.Equal(t, byte('u'), data[0])
+	pkgdef := memberData(t, a, PkgDef)
+	assert.True(t, bytes.HasSuffix(pkgdef, append(append([]byte("\n$$B\n"), data...), "\n$$\n"...)))
+
+	require.Len(t, p.Objects(), 1)
+	assert.Equal(t, "_go_.o", p.Objects()[0].Name)
+}
+
+func TestOpenMain(t *testing.T) {
+	p, err := Open(openArchive(t, "./test_data/main.a"))
+	require.NoError(t, err)
+	assert.True(t, p.Header.Main)
+	assert.Equal(t, "", p.Header.BuildID)
+}
+
+func TestOpenErrors(t *testing.T) {
+	var buf bytes.Buffer
+	writer := ar.NewWriter(&buf, ar.GNU)
+	require.NoError(t, writer.WriteHeader(&ar.Header{Name: "hello.txt", Mode: 0644}))
+	require.NoError(t, writer.Close())
+	notPackage := newArchive(t, buf.Bytes())
+	assert.False(t, IsPackage(notPackage))
+	_, err := Open(notPackage)
+	assert.ErrorIs(t, err, ErrNotPackage)
+
+	for _, tc := range []struct {
+		Description string
+		PkgDef      string
+	}{
+		{"Invalid object header", "go archive\n\n\n$$B\nu\n$$\n"},
+		{"Invalid build ID", "go object linux amd64 go1.21.0\nbuild id abc\n\n\n$$B\nu\n$$\n"},
+		{"Missing export data", "go object linux amd64 go1.21.0\n\n"},
+		{"Unterminated export data", "go object linux amd64 go1.21.0\n\n\n$$B\nu"},
+		{"Truncated export data", "go object linux amd64 go1.21.0\n\n\n$$B\nu\n$"},
+	} {
+		t.Run(tc.Description, func(t *testing.T) {
+			_, err := Open(testPackage(t, tc.PkgDef))
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestReplaceExportData(t *testing.T) {
+	original := openArchive(t, "./test_data/greet.a")
+	p, err := Open(original)
+	require.NoError(t, err)
+	v2, err := Open(openArchive(t, "./test_data/greet_v2.a"))
+	require.NoError(t, err)
+	exportData, err := io.ReadAll(v2.ExportData())
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	require.NoError(t, p.ReplaceExportData(&buf, exportData))
+	replaced := newArchive(t, buf.Bytes())
+	// Both archives were compiled by the same toolchain with the same build ID, so their __.PKGDEF
+	// members only differ in their export data.
+	assert.Equal(t, memberData(t, v2.Archive, PkgDef), memberData(t, replaced, PkgDef))
+	assert.Equal(t, memberData(t, original, "_go_.o"), memberData(t, replaced, "_go_.o"))
+	q, err := Open(replaced)
+	require.NoError(t, err)
+	assert.Equal(t, p.Header, q.Header)
+	data, err := io.ReadAll(q.ExportData())
+	require.NoError(t, err)
+	assert.Equal(t, exportData, data)
+}
+
+func TestReplaceExportDataUnchanged(t *testing.T) {
+	original, err := os.ReadFile("./test_data/greet.a")
+	require.NoError(t, err)
+	p, err := Open(newArchive(t, original))
+	require.NoError(t, err)
+	exportData, err := io.ReadAll(p.ExportData())
+	require.NoError(t, err)
+	var buf bytes.Buffer
+	require.NoError(t, p.ReplaceExportData(&buf, exportData))
+	// The Go toolchain pads odd-sized members with a NUL byte rather than a newline, so the final
+	// (padding) byte of the archive differs.
+	require.Equal(t, len(original), buf.Len())
+	assert.Equal(t, original[:len(original)-1], buf.Bytes()[:buf.Len()-1])
+}
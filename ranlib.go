@@ -0,0 +1,52 @@
+package ar
+
+import (
+	"io"
+	"io/fs"
+	"math"
+)
+
+// Ranlib writes to out a copy of the archive read from in, with a freshly generated symbol table
+// (like the ranlib command) listing the global symbols defined by the ELF, Mach-O and COFF object
+// files and short import objects in the archive. Any existing symbol table is replaced. The symbol
+// table is written in the format appropriate to the archive's variant ("/" for GNU-variant archives,
+// or "__.SYMDEF SORTED" for BSD-variant archives, or their 64-bit equivalents if necessary), followed
+// by a second linker member if the archive already had one; the archive's members are copied
+// unchanged.
+//
+// The size of the archive is determined from in if it has a Size or Stat method (as *bytes.Reader,
+// *io.SectionReader and *os.File do) or if it is an io.Seeker; otherwise, it is assumed to extend
+// until ReadAt returns io.EOF.
+//
+// Ranlib can't regenerate the symbol tables of thin archives, since their members' data sections are
+// stored in external files; use Edit with a Resolver to do so.
+func Ranlib(in io.ReaderAt, out io.Writer) error {
+	size, err := readerAtSize(in)
+	if err != nil {
+		return err
+	}
+	a, err := NewArchive(in, size)
+	if err != nil {
+		return err
+	}
+	return Edit(out, a, nil, EditOptions{
+		WriterOptions: WriterOptions{SymbolIndex: true},
+	})
+}
+
+// readerAtSize returns the number of bytes that can be read from r.
+func readerAtSize(r io.ReaderAt) (int64, error) {
+	switch r := r.(type) {
+	case interface{ Size() int64 }:
+		return r.Size(), nil
+	case interface{ Stat() (fs.FileInfo, error) }:
+		fi, err := r.Stat()
+		if err != nil {
+			return 0, err
+		}
+		return fi.Size(), nil
+	case io.Seeker:
+		return r.Seek(0, io.SeekEnd)
+	}
+	return math.MaxInt64, nil
+}
@@ -0,0 +1,83 @@
+package ar
+
+import (
+	"bytes"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// stripSymbolTable returns a copy of the archive at the given path without its symbol table.
+func stripSymbolTable(t *testing.T, path string, variant Variant) []byte {
+	hdrs, data := readMembers(t, path)
+	var buf bytes.Buffer
+	writer := NewWriterWithOptions(&buf, variant, WriterOptions{Buffer: true})
+	for i, hdr := range hdrs {
+		require.NoError(t, writer.WriteHeader(hdr))
+		_, err := writer.Write(data[i])
+		require.NoError(t, err)
+	}
+	require.NoError(t, writer.Close())
+	return buf.Bytes()
+}
+
+func TestRanlib(t *testing.T) {
+	// The symbol table generated for the stripped archive should be identical to the one generated by
+	// GNU ar.
+	stripped := stripSymbolTable(t, "./test_data/symbols_gnu.a", GNU)
+	var out bytes.Buffer
+	require.NoError(t, Ranlib(bytes.NewReader(stripped), &out))
+	expected, err := os.ReadFile("./test_data/symbols_gnu.a")
+	require.NoError(t, err)
+	assert.Equal(t, expected, out.Bytes())
+
+	// Existing symbol tables are replaced.
+	f, err := os.Open("./test_data/symbols_gnu.a")
+	require.NoError(t, err)
+	defer f.Close()
+	out.Reset()
+	require.NoError(t, Ranlib(f, &out))
+	assert.Equal(t, expected, out.Bytes())
+}
+
+func TestRanlibBSD(t *testing.T) {
+	stripped := stripSymbolTable(t, "./test_data/symbols_bsd.a", BSD)
+	var out bytes.Buffer
+	require.NoError(t, Ranlib(bytes.NewReader(stripped), &out))
+	archive, err := NewArchive(bytes.NewReader(out.Bytes()), int64(out.Len()))
+	require.NoError(t, err)
+	assert.Equal(t, BSD, archive.Variant())
+	table := archive.SymbolTable()
+	require.Len(t, table, 3)
+	assert.Equal(t, "add.o", table["add"].Name)
+	assert.Equal(t, "add.o", table["counter"].Name)
+	assert.Equal(t, "sub.o", table["sub"].Name)
+}
+
+func TestRanlibCOFF(t *testing.T) {
+	// The linker members should be regenerated in the format produced by Microsoft's tools.
+	f, err := os.Open("./test_data/coff_import.lib")
+	require.NoError(t, err)
+	defer f.Close()
+	var out bytes.Buffer
+	require.NoError(t, Ranlib(f, &out))
+	archive, err := NewArchive(bytes.NewReader(out.Bytes()), int64(out.Len()))
+	require.NoError(t, err)
+	expected := openArchive(t, "./test_data/coff_import.lib")
+	assert.Equal(t, expected.Symbols(), archive.Symbols())
+	assert.Equal(t, expected.COFFLinkerMember(), archive.COFFLinkerMember())
+	require.Len(t, archive.Files, len(expected.Files))
+	for i, f := range archive.Files {
+		assert.Equal(t, expected.Files[i].Name, f.Name)
+	}
+}
+
+func TestRanlibThin(t *testing.T) {
+	f, err := os.Open("./test_data/thin.a")
+	require.NoError(t, err)
+	defer f.Close()
+	var out bytes.Buffer
+	assert.ErrorIs(t, Ranlib(f, &out), ErrNoResolver)
+}
@@ -33,6 +33,10 @@ type Reader struct {
 	// r is the underlying archive file.
 	r *bufio.Reader
 
+	// seeker is the underlying archive file, if it supports seeking; it allows unread data sections to
+	// be skipped without reading them.
+	seeker io.ReadSeeker
+
 	// variant is the variant of the ar file format used by the archive.
 	variant Variant
 
@@ -48,14 +52,149 @@ type Reader struct {
 	// variant of the archive format, which stores the names of files that are too long to fit in a
 	// file name header field.
 	stringTable []byte
+
+	// offset is the byte offset of the next member's header, relative to the start of the archive file.
+	offset int64
+
+	// headerOffset and dataOffset are the byte offsets of the current member's header and data section
+	// respectively, relative to the start of the archive file.
+	headerOffset, dataOffset int64
+
+	// index is the zero-based position of the current member in the archive, counting special members
+	// such as symbol tables and string tables, or -1 if Next has not yet been called.
+	index int
+
+	// symbols is the archive's symbol table, in the order in which its entries appear in the archive,
+	// or nil if the archive has no symbol table.
+	symbols []Symbol
+
+	// symbolTableOffset and symbolTableIndex are the byte offset of the symbol table's header and its
+	// position in the archive.
+	symbolTableOffset int64
+	symbolTableIndex  int
+
+	// coffLinkerMember is the archive's second linker member, or nil if the archive has none (see
+	// COFFLinkerMember).
+	coffLinkerMember *COFFLinkerMember
+
+	// symbolOffsets maps the byte offsets of member headers to the names of the symbols that the
+	// archive's symbol table says those members define.
+	symbolOffsets map[int64][]string
+
+	// symbolTable maps the names of symbols in the archive's symbol table to the headers of the
+	// members that define them. It only contains entries for members that Next has already returned.
+	symbolTable map[string]*Header
+
+	// thin is true if the archive is a thin archive, in which the data sections of members other than
+	// the symbol and string tables are stored in external files rather than in the archive itself.
+	thin bool
+
+	// resolver opens the external files containing the data sections of thin archives' members.
+	resolver Resolver
+
+	// external is the header of the current member if its data section is stored in an external file
+	// (i.e. if the archive is thin).
+	external *Header
+
+	// externalData is the data section of the current member, if it is stored in an external file that
+	// has been opened by resolver; reads from it are limited to the size recorded in the member's
+	// header.
+	externalData io.Reader
+
+	// externalFile is the external file from which externalData is read, which is closed when the
+	// Reader advances to the next member.
+	externalFile io.Closer
+
+	// strict is true if the Reader rejects malformed headers rather than making a best effort to read
+	// them.
+	strict bool
+
+	// size is the size of the archive file in bytes, or -1 if it is unknown. It is only determined in
+	// strict mode.
+	size int64
+
+	// limits are the resource limits imposed on the archive (see ReaderOptions).
+	limits ReaderOptions
+
+	// members is the number of members that Next has returned so far.
+	members int
+
+	// totalSize is the sum of the sizes of the data sections of the members that Next has returned so
+	// far.
+	totalSize int64
+}
+
+// ReaderOptions specifies optional behaviour for a Reader created with NewReaderWithOptions.
+type ReaderOptions struct {
+	// Resolver opens the external files containing the data sections of the members of thin archives.
+	// If it is nil, members of thin archives can still be listed with Next, but Read returns
+	// ErrNoResolver.
+	Resolver Resolver
+
+	// Strict causes the Reader to validate each member's header, returning an *ErrHeader if it is
+	// malformed. Without it, the Reader makes a best effort to read malformed headers: for example,
+	// numeric fields that can't be parsed are treated as 0 (although negative sizes are always
+	// rejected, since there's no way to read such a member). In strict mode, headers must end with the
+	// "`\n" terminator, their numeric fields must be valid non-negative integers (or blank), and
+	// members' sizes must not exceed the amount of data remaining in the archive.
+	Strict bool
+
+	// The following fields limit the resources that the Reader will consume while reading an
+	// archive, which is useful when reading archives from untrusted sources. If a limit is exceeded,
+	// the Reader returns an *ErrLimit. A limit of 0 means that the corresponding resource is unlimited.
+
+	// MaxStringTableSize is the maximum size in bytes of a GNU-variant archive's string table, which
+	// the Reader holds in memory.
+	MaxStringTableSize int64
+
+	// MaxSymbolTableSize is the maximum size in bytes of an archive's symbol table, which the Reader
+	// holds in memory.
+	MaxSymbolTableSize int64
+
+	// MaxNameLength is the maximum length in bytes of a member's file name.
+	MaxNameLength int
+
+	// MaxMembers is the maximum number of members that the archive may contain, not including special
+	// members such as symbol tables and string tables.
+	MaxMembers int
+
+	// MaxTotalSize is the maximum total size in bytes of the data sections of the archive's members,
+	// not including special members such as symbol tables and string tables.
+	MaxTotalSize int64
 }
 
 // NewReader creates a new reader reading from r. It returns an error if the global archive
 // header is missing or malformed.
 func NewReader(r io.Reader) (*Reader, error) {
+	return NewReaderWithOptions(r, ReaderOptions{})
+}
+
+// NewReaderWithOptions creates a new reader reading from r, with the optional behaviour specified by
+// opts. It returns an error if the global archive header is missing or malformed.
+func NewReaderWithOptions(r io.Reader, opts ReaderOptions) (*Reader, error) {
 	rd := &Reader{
-		r:       bufio.NewReader(r),
-		variant: BSD,
+		r:        bufio.NewReader(r),
+		variant:  BSD,
+		offset:   int64(len(GLOBAL_HEADER)),
+		resolver: opts.Resolver,
+		strict:   opts.Strict,
+		size:     -1,
+		limits:   opts,
+		index:    -1,
+	}
+	if seeker, ok := r.(io.ReadSeeker); ok {
+		rd.seeker = seeker
+		// In strict mode, determine the size of the archive so members' sizes can be validated.
+		if rd.strict {
+			if cur, err := seeker.Seek(0, io.SeekCurrent); err == nil {
+				if end, err := seeker.Seek(0, io.SeekEnd); err == nil {
+					if _, err := seeker.Seek(cur, io.SeekStart); err != nil {
+						return nil, fmt.Errorf("ar: %w", err)
+					}
+					rd.size = end - cur
+				}
+			}
+		}
 	}
 	// Ensure the global archive header is valid.
 	var hdr bytes.Buffer
@@ -65,7 +204,14 @@ func NewReader(r io.Reader) (*Reader, error) {
 		}
 		return nil, fmt.Errorf("ar: %w", err)
 	}
-	if string(hdr.Bytes()) != GLOBAL_HEADER {
+	switch string(hdr.Bytes()) {
+	case GLOBAL_HEADER:
+	// Thin archives are only produced by GNU ar, and always use the GNU variant.
+	case THIN_GLOBAL_HEADER:
+		rd.thin = true
+		rd.variant = GNU
+		return rd, nil
+	default:
 		return nil, ErrInvalidGlobalHeader
 	}
 	// Peek at the file name in the archive's first header to determine whether the archive contains a
@@ -93,31 +239,41 @@ func (rd *Reader) string(b []byte) string {
 	return string(b[0 : i+1])
 }
 
-func (rd *Reader) numeric(b []byte) int64 {
-	i := len(b) - 1
-	for i > 0 && b[i] == 32 {
-		i--
-	}
-
-	n, _ := strconv.ParseInt(string(b[0:i+1]), 10, 64)
+func (rd *Reader) numeric(b []byte) (int64, error) {
+	return rd.integer(b, 10)
+}
 
-	return n
+func (rd *Reader) octal(b []byte) (int64, error) {
+	return rd.integer(b, 8)
 }
 
-func (rd *Reader) octal(b []byte) int64 {
-	i := len(b) - 1
-	for i > 0 && b[i] == 32 {
-		i--
+// integer parses a space-padded integer field in the given base. Fields consisting entirely of spaces
+// have the value 0. If the field is malformed, integer returns 0 and an error.
+func (rd *Reader) integer(b []byte, base int) (int64, error) {
+	str := strings.TrimRight(string(b), " ")
+	if str == "" {
+		return 0, nil
 	}
-
-	n, _ := strconv.ParseInt(string(b[0:i+1]), 8, 64)
-
-	return n
+	n, err := strconv.ParseInt(str, base, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid numeric field %q", b)
+	}
+	return n, nil
 }
 
 func (rd *Reader) skipUnread() error {
 	skip := rd.nb + rd.pad
 	rd.nb, rd.pad = 0, 0
+	// If the underlying archive file supports seeking, seek past any of the unread data that hasn't
+	// already been buffered rather than reading it. Not all files that implement io.Seeker actually
+	// support seeking (e.g. pipes), so fall back to reading the data if seeking fails.
+	if buffered := int64(rd.r.Buffered()); rd.seeker != nil && skip > buffered {
+		if _, err := rd.seeker.Seek(skip-buffered, io.SeekCurrent); err == nil {
+			rd.r.Reset(rd.seeker)
+			return nil
+		}
+		rd.seeker = nil
+	}
 	_, err := io.CopyN(ioutil.Discard, rd.r, skip)
 	return err
 }
@@ -127,29 +283,150 @@ func (rd *Reader) Variant() Variant {
 	return rd.variant
 }
 
+// Thin returns whether the archive is a thin archive, in which the data sections of members are
+// stored in external files rather than in the archive itself. The names of the members of thin
+// archives are the paths to these external files, relative to the directory containing the archive.
+func (rd *Reader) Thin() bool {
+	return rd.thin
+}
+
+// Symbols returns the entries in the archive's symbol table, in the order in which they appear in
+// the archive. It returns nil if the archive has no symbol table; because the symbol table is always
+// the first member of an archive, this is also the case if Next has not yet been called.
+func (rd *Reader) Symbols() []Symbol {
+	return rd.symbols
+}
+
+// SymbolTable returns a map of the names of symbols in the archive's symbol table to the headers of
+// the archive members that define them. Because Reader reads the archive sequentially, the map only
+// contains symbols defined by members that Next has already returned; it is complete once Next has
+// returned io.EOF. If more than one member defines the same symbol, the map contains the first of
+// them. SymbolTable returns nil if the archive has no symbol table.
+func (rd *Reader) SymbolTable() map[string]*Header {
+	return rd.symbolTable
+}
+
+// COFFLinkerMember returns the archive's second linker member, which archives produced by
+// Microsoft's tools (e.g. Windows import libraries) contain in addition to the symbol table returned
+// by Symbols. It returns nil if the archive has no second linker member; because the linker members
+// are always the first members of an archive, this is also the case if Next has not yet been called.
+func (rd *Reader) COFFLinkerMember() *COFFLinkerMember {
+	return rd.coffLinkerMember
+}
+
+// HeaderOffset returns the byte offset of the current member's header, relative to the start of the
+// archive file. If Next returned an error, it is the offset of the header of the member that caused
+// the error.
+func (rd *Reader) HeaderOffset() int64 {
+	return rd.headerOffset
+}
+
+// DataOffset returns the byte offset of the current member's data section, relative to the start of
+// the archive file. For members of thin archives, whose data sections are stored in external files,
+// it is the offset immediately following the member's header.
+func (rd *Reader) DataOffset() int64 {
+	return rd.dataOffset
+}
+
+// readSymbolTable reads the current entry in the archive, which must be a symbol table, and decodes
+// it using parse.
+func (rd *Reader) readSymbolTable(parse func([]byte) ([]Symbol, error)) error {
+	if rd.symbols != nil {
+		return &ErrSymbolTable{Offset: rd.headerOffset, Index: rd.index, Err: errors.New("archive contains multiple symbol tables")}
+	}
+	if rd.nb < 0 {
+		return &ErrSymbolTable{Offset: rd.headerOffset, Index: rd.index, Err: errors.New("negative size")}
+	}
+	if err := rd.checkLimit("symbol table size", rd.nb, rd.limits.MaxSymbolTableSize); err != nil {
+		return err
+	}
+	buf := make([]byte, rd.nb)
+	if _, err := rd.Read(buf); err != nil && err != io.EOF {
+		return &ErrSymbolTable{Offset: rd.headerOffset, Index: rd.index, Err: err}
+	}
+	symbols, err := parse(buf)
+	if err != nil {
+		return &ErrSymbolTable{Offset: rd.headerOffset, Index: rd.index, Err: err}
+	}
+	rd.symbols = symbols
+	rd.symbolTableOffset, rd.symbolTableIndex = rd.headerOffset, rd.index
+	rd.symbolOffsets = map[int64][]string{}
+	rd.symbolTable = map[string]*Header{}
+	for _, symbol := range symbols {
+		rd.symbolOffsets[symbol.Offset] = append(rd.symbolOffsets[symbol.Offset], symbol.Name)
+	}
+	return nil
+}
+
+// readCOFFLinkerMember reads the current entry in the archive, which must be a second linker member.
+func (rd *Reader) readCOFFLinkerMember() error {
+	if rd.nb < 0 {
+		return &ErrSymbolTable{Offset: rd.headerOffset, Index: rd.index, Err: errors.New("second linker member: negative size")}
+	}
+	if err := rd.checkLimit("symbol table size", rd.nb, rd.limits.MaxSymbolTableSize); err != nil {
+		return err
+	}
+	buf := make([]byte, rd.nb)
+	if _, err := rd.Read(buf); err != nil && err != io.EOF {
+		return &ErrSymbolTable{Offset: rd.headerOffset, Index: rd.index, Err: err}
+	}
+	lm, err := parseCOFFLinkerMember(buf)
+	if err != nil {
+		return &ErrSymbolTable{Offset: rd.headerOffset, Index: rd.index, Err: fmt.Errorf("second linker member: %w", err)}
+	}
+	rd.coffLinkerMember = lm
+	return nil
+}
+
 // Next skips to the next file in the archive file.
 // Returns a Header which contains the metadata about the
 // file in the archive. io.EOF is returned at the end of the input.
 func (rd *Reader) Next() (*Header, error) {
+	if err := rd.closeExternal(); err != nil {
+		return nil, err
+	}
 	err := rd.skipUnread()
 	if err != nil {
+		if rd.strict && errors.Is(err, io.EOF) {
+			return nil, &ErrHeader{Offset: rd.headerOffset, Index: rd.index, Err: errors.New("data section is truncated")}
+		}
 		return nil, err
 	}
 
+	headerOffset := rd.offset
 	headerBuf := make([]byte, HEADER_BYTE_SIZE)
 	if _, err := io.ReadFull(rd.r, headerBuf); err != nil {
+		if rd.strict && err == io.ErrUnexpectedEOF {
+			return nil, &ErrHeader{Offset: headerOffset, Index: rd.index + 1, Err: errors.New("header is truncated")}
+		}
 		return nil, err
 	}
+	rd.index++
+	rd.headerOffset = headerOffset
+	rd.dataOffset = headerOffset + HEADER_BYTE_SIZE
 
 	header := new(Header)
 	s := slicer(headerBuf)
 
+	// Malformed numeric fields are treated as 0, unless the Reader is in strict mode.
+	var fieldErr error
+	field := func(n int64, err error) int64 {
+		if err != nil && fieldErr == nil {
+			fieldErr = err
+		}
+		return n
+	}
 	header.Name = rd.string(s.next(16))
-	header.ModTime = time.Unix(rd.numeric(s.next(12)), 0)
-	header.Uid = int(rd.numeric(s.next(6)))
-	header.Gid = int(rd.numeric(s.next(6)))
-	header.Mode = rd.octal(s.next(8))
-	header.Size = rd.numeric(s.next(10))
+	header.ModTime = time.Unix(field(rd.numeric(s.next(12))), 0)
+	header.Uid = int(field(rd.numeric(s.next(6))))
+	header.Gid = int(field(rd.numeric(s.next(6))))
+	header.Mode = field(rd.octal(s.next(8)))
+	header.Size = field(rd.numeric(s.next(10)))
+	if rd.strict {
+		if err := rd.validateHeader(header, fieldErr, s.next(2)); err != nil {
+			return nil, err
+		}
+	}
 
 	rd.nb = int64(header.Size)
 	if header.Size%2 == 1 {
@@ -157,25 +434,48 @@ func (rd *Reader) Next() (*Header, error) {
 	} else {
 		rd.pad = 0
 	}
+	rd.offset += HEADER_BYTE_SIZE + rd.nb + rd.pad
 
 	switch rd.variant {
 	case GNU:
 		switch header.Name {
 		// The special file name "/" indicates that the data section contains a symbol table.
-		case "/":
-			// The symbol table should be invisible to the caller - skip over it.
+		// The special file name "/SYM64/" indicates that the data section contains a symbol table with
+		// 64-bit member offsets, which GNU ar writes instead of "/" when the archive is too large for
+		// member offsets to be represented in 32 bits.
+		case "/", "/SYM64/":
+			// Archives produced by Microsoft's tools contain a second "/" member immediately after the
+			// first, which contains the same symbols in a different format.
+			if header.Name == "/" && rd.symbols != nil && rd.coffLinkerMember == nil && rd.index == rd.symbolTableIndex+1 {
+				if err := rd.readCOFFLinkerMember(); err != nil {
+					return nil, err
+				}
+				return rd.Next()
+			}
+			wide := header.Name == "/SYM64/"
+			if err := rd.readSymbolTable(func(b []byte) ([]Symbol, error) { return parseGNUSymbolTable(b, wide) }); err != nil {
+				return nil, err
+			}
+			// The symbol table should be invisible to the caller - return the header for the next file in
+			// the archive. Its contents are available via Symbols and SymbolTable.
 			return rd.Next()
 		// The special file name "//" indicates that the data section contains a string table. The string
 		// table contains the names of files in the archive that are >= 15 bytes long, delimited with
 		// newlines. Store it, so we can resolve long file names when we encounter them later.
 		case "//":
 			if rd.stringTable != nil {
-				return nil, &ErrStringTable{Err: errors.New("archive contains multiple string tables")}
+				return nil, &ErrStringTable{Offset: rd.headerOffset, Index: rd.index, Err: errors.New("archive contains multiple string tables")}
+			}
+			if rd.nb < 0 {
+				return nil, &ErrStringTable{Offset: rd.headerOffset, Index: rd.index, Err: errors.New("negative size")}
+			}
+			if err := rd.checkLimit("string table size", rd.nb, rd.limits.MaxStringTableSize); err != nil {
+				return nil, err
 			}
 			buf := make([]byte, rd.nb)
 			_, err := rd.Read(buf)
 			if err != nil {
-				return nil, &ErrStringTable{Err: err}
+				return nil, &ErrStringTable{Offset: rd.headerOffset, Index: rd.index, Err: err}
 			}
 			rd.stringTable = buf
 			// The string table should be invisible to the caller - return the header for the first real file
@@ -190,17 +490,60 @@ func (rd *Reader) Next() (*Header, error) {
 			return nil, err
 		}
 		// The special file name "__.SYMDEF" (and variations of it) indicates that the data section contains a symbol table.
-		if header.Name == "__.SYMDEF" || header.Name == "__.SYMDEF SORTED" || header.Name == "__.SYMDEF_64" {
-			// The symbol table should be invisible to the caller - skip over it.
+		switch header.Name {
+		case "__.SYMDEF", "__.SYMDEF SORTED", "__.SYMDEF_64", "__.SYMDEF_64 SORTED":
+			wide := strings.HasPrefix(header.Name, "__.SYMDEF_64")
+			if err := rd.readSymbolTable(func(b []byte) ([]Symbol, error) { return parseBSDSymbolTable(b, wide) }); err != nil {
+				return nil, err
+			}
+			// The symbol table should be invisible to the caller - return the header for the next file in
+			// the archive. Its contents are available via Symbols and SymbolTable.
 			return rd.Next()
 		}
 	}
 
-	// The file name has now been resolved; make sure it doesn't contain any illegal characters.
-	if strings.Contains(header.Name, "/") {
+	// Negative sizes are rejected even if the Reader isn't in strict mode, since there's no way to make
+	// a best effort to read such a member.
+	if header.Size < 0 {
+		return nil, &ErrHeader{Offset: rd.headerOffset, Index: rd.index, Err: errors.New("negative size")}
+	}
+
+	// The data sections of thin archives' members (other than the symbol and string tables) are stored
+	// in external files, so there is no data to read from the archive itself.
+	if rd.thin {
+		rd.offset -= rd.nb + rd.pad
+		rd.nb, rd.pad = 0, 0
+		rd.external = header
+	}
+
+	// The file name has now been resolved; make sure it doesn't contain any illegal characters. The
+	// names of thin archives' members are paths, so they may legitimately contain "/".
+	if !rd.thin && strings.Contains(header.Name, "/") {
 		return nil, &ErrFileName{
-			Name: header.Name,
-			Err:  errors.New("file name contains illegal '/'"),
+			Name:   header.Name,
+			Offset: rd.headerOffset,
+			Index:  rd.index,
+			Err:    errors.New("file name contains illegal '/'"),
+		}
+	}
+
+	if err := rd.checkLimit("file name length", int64(len(header.Name)), int64(rd.limits.MaxNameLength)); err != nil {
+		return nil, err
+	}
+	rd.members++
+	if err := rd.checkLimit("member count", int64(rd.members), int64(rd.limits.MaxMembers)); err != nil {
+		return nil, err
+	}
+	rd.totalSize += header.Size
+	if err := rd.checkLimit("total member size", rd.totalSize, rd.limits.MaxTotalSize); err != nil {
+		return nil, err
+	}
+
+	rd.dataOffset = rd.offset - rd.nb - rd.pad
+
+	for _, name := range rd.symbolOffsets[headerOffset] {
+		if _, present := rd.symbolTable[name]; !present {
+			rd.symbolTable[name] = header
 		}
 	}
 
@@ -210,8 +553,10 @@ func (rd *Reader) Next() (*Header, error) {
 func (rd *Reader) parseGNUFileName(header *Header) error {
 	if len(header.Name) == 0 {
 		return &ErrFileName{
-			Name: header.Name,
-			Err:  errors.New("zero-length file name"),
+			Name:   header.Name,
+			Offset: rd.headerOffset,
+			Index:  rd.index,
+			Err:    errors.New("zero-length file name"),
 		}
 	}
 	// A file name conisting of "/" followed by an integer indicates that this file has a long name
@@ -220,32 +565,60 @@ func (rd *Reader) parseGNUFileName(header *Header) error {
 	if header.Name[0] == '/' {
 		if rd.stringTable == nil {
 			return &ErrFileName{
-				Name: header.Name,
-				Err:  errors.New("missing string table"),
+				Name:   header.Name,
+				Offset: rd.headerOffset,
+				Index:  rd.index,
+				Err:    errors.New("missing string table"),
 			}
 		}
 		start, err := strconv.Atoi(header.Name[1:])
-		if err != nil || start > len(rd.stringTable) {
+		if err != nil || start < 0 || start > len(rd.stringTable) {
 			return &ErrFileName{
-				Name: header.Name,
-				Err:  errors.New("invalid string table offset"),
+				Name:   header.Name,
+				Offset: rd.headerOffset,
+				Index:  rd.index,
+				Err:    errors.New("invalid string table offset"),
 			}
 		}
 		tableEntry := rd.stringTable[start:]
-		end := bytes.IndexByte(tableEntry, '\n')
+		end := bytes.IndexAny(tableEntry, "\n\x00")
 		if end == -1 {
-			return &ErrStringTable{Err: errors.New("missing trailing newline")}
+			return &ErrStringTable{Offset: rd.headerOffset, Index: rd.index, Err: errors.New("missing trailing newline")}
+		}
+		if end == 0 {
+			return &ErrFileName{
+				Name:   header.Name,
+				Offset: rd.headerOffset,
+				Index:  rd.index,
+				Err:    errors.New("empty string table entry"),
+			}
+		}
+		// Microsoft's tools terminate the file names in the string table with NUL bytes, and don't
+		// append "/" to them.
+		if tableEntry[end] == 0 {
+			header.Name = string(tableEntry[:end])
+			return nil
 		}
 		header.Name = string(tableEntry[:end])
 	}
 	// GNU ar appends "/" to all file names, regardless of where they are stored.
 	if header.Name[len(header.Name)-1] != '/' {
 		return &ErrFileName{
-			Name: header.Name,
-			Err:  errors.New("file name is missing trailing '/'"),
+			Name:   header.Name,
+			Offset: rd.headerOffset,
+			Index:  rd.index,
+			Err:    errors.New("file name is missing trailing '/'"),
 		}
 	}
 	header.Name = strings.TrimRight(header.Name, "/")
+	if header.Name == "" {
+		return &ErrFileName{
+			Name:   header.Name,
+			Offset: rd.headerOffset,
+			Index:  rd.index,
+			Err:    errors.New("zero-length file name"),
+		}
+	}
 	return nil
 }
 
@@ -256,16 +629,35 @@ func (rd *Reader) parseBSDFileName(header *Header) error {
 		length, err := strconv.Atoi(header.Name[3:])
 		if err != nil {
 			return &ErrFileName{
-				Name: header.Name,
-				Err:  errors.New("invalid long file name length"),
+				Name:   header.Name,
+				Offset: rd.headerOffset,
+				Index:  rd.index,
+				Err:    errors.New("invalid long file name length"),
+			}
+		}
+		if length < 0 || int64(length) > header.Size {
+			return &ErrFileName{
+				Name:   header.Name,
+				Offset: rd.headerOffset,
+				Index:  rd.index,
+				Err:    errors.New("long file name length exceeds member size"),
+			}
+		}
+		// The prepended data may be padded with up to 7 trailing nulls (see below), which don't count
+		// towards the file name's length.
+		if limit := rd.limits.MaxNameLength; limit > 0 {
+			if err := rd.checkLimit("file name length", int64(length), int64(limit+7)); err != nil {
+				return err
 			}
 		}
 		header.Size -= int64(length)
 		b := make([]byte, length)
 		if _, err := rd.Read(b); err != nil {
 			return &ErrFileName{
-				Name: header.Name,
-				Err:  err,
+				Name:   header.Name,
+				Offset: rd.headerOffset,
+				Index:  rd.index,
+				Err:    err,
 			}
 		}
 		// Some implementations (e.g. llvm-ar) append an indeterminate number of trailing nulls to the
@@ -275,8 +667,48 @@ func (rd *Reader) parseBSDFileName(header *Header) error {
 	return nil
 }
 
-// Read reads data from the current entry in the archive.
+// checkLimit returns an *ErrLimit if value exceeds the given resource limit, which is unlimited if it
+// is 0.
+func (rd *Reader) checkLimit(resource string, value, limit int64) error {
+	if limit > 0 && value > limit {
+		return &ErrLimit{Resource: resource, Value: value, Limit: limit, Offset: rd.headerOffset, Index: rd.index}
+	}
+	return nil
+}
+
+// validateHeader validates the current member's header, which has been parsed in strict mode, given
+// the first error encountered while parsing its numeric fields (if any) and its terminator field.
+func (rd *Reader) validateHeader(header *Header, fieldErr error, terminator []byte) error {
+	offset := rd.headerOffset
+	if string(terminator) != "`\n" {
+		return &ErrHeader{Offset: offset, Index: rd.index, Err: fmt.Errorf("invalid terminator %q", terminator)}
+	}
+	if fieldErr != nil {
+		return &ErrHeader{Offset: offset, Index: rd.index, Err: fieldErr}
+	}
+	if header.ModTime.Unix() < 0 || header.Uid < 0 || header.Gid < 0 || header.Mode < 0 {
+		return &ErrHeader{Offset: offset, Index: rd.index, Err: errors.New("negative numeric field")}
+	}
+	if header.Size < 0 {
+		return &ErrHeader{Offset: offset, Index: rd.index, Err: errors.New("negative size")}
+	}
+	// The data sections of thin archives' members are stored in external files, apart from those of
+	// the symbol and string tables.
+	if rd.thin && !specialGNUName(header.Name) {
+		return nil
+	}
+	if rd.size >= 0 && header.Size > rd.size-offset-HEADER_BYTE_SIZE {
+		return &ErrHeader{Offset: offset, Index: rd.index, Err: fmt.Errorf("size %d exceeds remaining archive data", header.Size)}
+	}
+	return nil
+}
+
+// Read reads data from the current entry in the archive. If the archive is thin, the data is read
+// from the external file opened by the Resolver given in the Reader's options.
 func (rd *Reader) Read(b []byte) (n int, err error) {
+	if rd.external != nil {
+		return rd.readExternal(b)
+	}
 	if rd.nb == 0 {
 		return 0, io.EOF
 	}
@@ -2,6 +2,8 @@ package ar
 
 import (
 	"bytes"
+	"encoding/binary"
+	"errors"
 	"fmt"
 	"io"
 	"os"
@@ -66,3 +68,460 @@ func TestLongFilenames(t *testing.T) {
 		})
 	}
 }
+
+func TestGNUSymbolTable(t *testing.T) {
+	for _, tc := range []struct {
+		Description string
+		ArchivePath string
+		Symbols     []Symbol
+	}{
+		{
+			Description: "32-bit symbol table",
+			ArchivePath: "./test_data/symbols_gnu.a",
+			Symbols: []Symbol{
+				{Name: "add", Offset: 100},
+				{Name: "counter", Offset: 100},
+				{Name: "sub", Offset: 896},
+			},
+		},
+		{
+			Description: "64-bit symbol table",
+			ArchivePath: "./test_data/symbols_gnu64.a",
+			Symbols: []Symbol{
+				{Name: "add", Offset: 116},
+				{Name: "counter", Offset: 116},
+				{Name: "sub", Offset: 912},
+			},
+		},
+	} {
+		t.Run(tc.Description, func(t *testing.T) {
+			f, err := os.Open(tc.ArchivePath)
+			require.NoError(t, err)
+			defer f.Close()
+			reader, err := NewReader(f)
+			require.NoError(t, err)
+			assert.Equal(t, GNU, reader.Variant())
+			var names []string
+			for {
+				hdr, err := reader.Next()
+				if err == io.EOF {
+					break
+				}
+				require.NoError(t, err)
+				names = append(names, hdr.Name)
+			}
+			assert.Equal(t, []string{"add.o", "sub.o"}, names)
+			assert.Equal(t, tc.Symbols, reader.Symbols())
+			table := reader.SymbolTable()
+			require.Len(t, table, 3)
+			assert.Equal(t, "add.o", table["add"].Name)
+			assert.Equal(t, "add.o", table["counter"].Name)
+			assert.Equal(t, "sub.o", table["sub"].Name)
+		})
+	}
+}
+
+func TestMalformedGNUSymbolTable(t *testing.T) {
+	for _, tc := range []struct {
+		Description string
+		Data        string
+	}{
+		{"Truncated symbol count", "\x00\x00"},
+		{"Truncated offset array", "\x00\x00\x00\x02\x00\x00\x00\x08"},
+		{"Truncated symbol name list", "\x00\x00\x00\x02\x00\x00\x00\x08\x00\x00\x00\x08foo\x00bar"},
+	} {
+		t.Run(tc.Description, func(t *testing.T) {
+			var buf bytes.Buffer
+			buf.WriteString(GLOBAL_HEADER)
+			fmt.Fprintf(&buf, "%-16s%-12d%-6d%-6d%-8o%-10d`\n", "/", 0, 0, 0, 0, len(tc.Data))
+			buf.WriteString(tc.Data)
+			if len(tc.Data)%2 == 1 {
+				buf.WriteByte('\n')
+			}
+			reader, err := NewReader(&buf)
+			require.NoError(t, err)
+			_, err = reader.Next()
+			var symErr *ErrSymbolTable
+			assert.ErrorAs(t, err, &symErr)
+		})
+	}
+}
+
+func TestGNUSymbolTableNegativeSize(t *testing.T) {
+	archive := GLOBAL_HEADER + fmt.Sprintf("%-16s%-12d%-6d%-6d%-8o%-10d`\n", "/", 0, 0, 0, 0, -4)
+	reader, err := NewReader(strings.NewReader(archive))
+	require.NoError(t, err)
+	_, err = reader.Next()
+	var symErr *ErrSymbolTable
+	assert.ErrorAs(t, err, &symErr)
+}
+
+func TestBSDSymbolTable(t *testing.T) {
+	f, err := os.Open("./test_data/symbols_bsd.a")
+	require.NoError(t, err)
+	defer f.Close()
+	reader, err := NewReader(f)
+	require.NoError(t, err)
+	assert.Equal(t, BSD, reader.Variant())
+	var names []string
+	for {
+		hdr, err := reader.Next()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		names = append(names, hdr.Name)
+	}
+	assert.Equal(t, []string{"add.o", "sub.o"}, names)
+	assert.Equal(t, []Symbol{
+		{Name: "add", Offset: 128},
+		{Name: "counter", Offset: 128},
+		{Name: "sub", Offset: 936},
+	}, reader.Symbols())
+	table := reader.SymbolTable()
+	require.Len(t, table, 3)
+	assert.Equal(t, "add.o", table["add"].Name)
+	assert.Equal(t, "add.o", table["counter"].Name)
+	assert.Equal(t, "sub.o", table["sub"].Name)
+}
+
+func TestBSDSymbolTableVariants(t *testing.T) {
+	for _, tc := range []struct {
+		Name string
+		Wide bool
+	}{
+		{"__.SYMDEF SORTED", false},
+		{"__.SYMDEF_64", true},
+	} {
+		t.Run(tc.Name, func(t *testing.T) {
+			word := func(b []byte, x int) []byte {
+				if tc.Wide {
+					return binary.LittleEndian.AppendUint64(b, uint64(x))
+				}
+				return binary.LittleEndian.AppendUint32(b, uint32(x))
+			}
+			pool := "alpha\x00beta\x00"
+			size := 4
+			if tc.Wide {
+				size = 8
+			}
+			symdefLen := 6*size + len(pool)
+			symdefLen += symdefLen % 2
+			nameLen := 0
+			if strings.Contains(tc.Name, " ") {
+				nameLen = len(tc.Name)
+			}
+			offset := len(GLOBAL_HEADER) + HEADER_BYTE_SIZE + nameLen + symdefLen
+			var symdef []byte
+			symdef = word(symdef, 4*size)
+			symdef = word(symdef, 0)
+			symdef = word(symdef, offset)
+			symdef = word(symdef, 6)
+			symdef = word(symdef, offset)
+			symdef = word(symdef, len(pool))
+			symdef = append(symdef, pool...)
+
+			var buf bytes.Buffer
+			writer := NewWriter(&buf, BSD)
+			require.NoError(t, writer.WriteHeader(&Header{Name: tc.Name, Size: int64(len(symdef))}))
+			_, err := writer.Write(symdef)
+			require.NoError(t, err)
+			require.NoError(t, writer.WriteHeader(&Header{Name: "greek.o", Size: 2}))
+			_, err = writer.Write([]byte("ok"))
+			require.NoError(t, err)
+			require.NoError(t, writer.Close())
+
+			reader, err := NewReader(&buf)
+			require.NoError(t, err)
+			hdr, err := reader.Next()
+			require.NoError(t, err)
+			assert.Equal(t, "greek.o", hdr.Name)
+			assert.Equal(t, []Symbol{
+				{Name: "alpha", Offset: int64(offset)},
+				{Name: "beta", Offset: int64(offset)},
+			}, reader.Symbols())
+			assert.Equal(t, map[string]*Header{"alpha": hdr, "beta": hdr}, reader.SymbolTable())
+		})
+	}
+}
+
+func TestThinArchive(t *testing.T) {
+	f, err := os.Open("./test_data/thin.a")
+	require.NoError(t, err)
+	defer f.Close()
+	reader, err := NewReaderWithOptions(f, ReaderOptions{Resolver: DirResolver("./test_data")})
+	require.NoError(t, err)
+	assert.True(t, reader.Thin())
+	assert.Equal(t, GNU, reader.Variant())
+	for _, name := range []string{"objs/add.o", "objs/sub/a_long_object_name.o", "objs/h.txt"} {
+		hdr, err := reader.Next()
+		require.NoError(t, err)
+		assert.Equal(t, name, hdr.Name)
+		expected, err := os.ReadFile("./test_data/" + name)
+		require.NoError(t, err)
+		assert.Equal(t, int64(len(expected)), hdr.Size)
+		// Only read part of the first member, to ensure the rest is skipped correctly.
+		if name == "objs/add.o" {
+			b := make([]byte, 4)
+			_, err := io.ReadFull(reader, b)
+			require.NoError(t, err)
+			assert.Equal(t, expected[:4], b)
+			continue
+		}
+		actual, err := io.ReadAll(reader)
+		require.NoError(t, err)
+		assert.Equal(t, expected, actual)
+	}
+	_, err = reader.Next()
+	assert.ErrorIs(t, err, io.EOF)
+	table := reader.SymbolTable()
+	assert.Equal(t, "objs/add.o", table["add"].Name)
+	assert.Equal(t, "objs/sub/a_long_object_name.o", table["sub"].Name)
+}
+
+func TestThinArchiveWithoutResolver(t *testing.T) {
+	f, err := os.Open("./test_data/thin.a")
+	require.NoError(t, err)
+	defer f.Close()
+	reader, err := NewReader(f)
+	require.NoError(t, err)
+	assert.True(t, reader.Thin())
+	hdr, err := reader.Next()
+	require.NoError(t, err)
+	assert.Equal(t, "objs/add.o", hdr.Name)
+	_, err = reader.Read(make([]byte, 1))
+	assert.ErrorIs(t, err, ErrNoResolver)
+}
+
+func TestStrictHeaders(t *testing.T) {
+	header := func(name, modTime, size, terminator string) string {
+		return fmt.Sprintf("%-16s%-12s%-6s%-6s%-8s%-10s%s", name, modTime, "0", "0", "644", size, terminator)
+	}
+	member := header("ok.txt/", "1361157466", "2", "`\n") + "ok"
+	offset := int64(len(GLOBAL_HEADER) + len(member))
+	for _, tc := range []struct {
+		Description string
+		Header      string
+		Error       string
+		// AlwaysInvalid indicates that lenient Readers also reject the header.
+		AlwaysInvalid bool
+	}{
+		{
+			Description: "Invalid terminator",
+			Header:      header("bad.txt/", "0", "0", "\n\n"),
+			Error:       `invalid terminator "\n\n"`,
+		},
+		{
+			Description: "Non-numeric field",
+			Header:      header("bad.txt/", "yesterday", "0", "`\n"),
+			Error:       `invalid numeric field "yesterday   "`,
+		},
+		{
+			Description:   "Negative size",
+			Header:        header("bad.txt/", "0", "-1", "`\n"),
+			Error:         "negative size",
+			AlwaysInvalid: true,
+		},
+		{
+			Description: "Size exceeding remaining input",
+			Header:      header("bad.txt/", "0", "100", "`\n") + "short",
+			Error:       "size 100 exceeds remaining archive data",
+		},
+		{
+			Description: "Truncated header",
+			Header:      header("bad.txt/", "0", "0", "`\n")[:30],
+			Error:       "header is truncated",
+		},
+	} {
+		t.Run(tc.Description, func(t *testing.T) {
+			archive := GLOBAL_HEADER + member + tc.Header
+
+			// Lenient Readers make a best effort to read the malformed header.
+			reader, err := NewReader(strings.NewReader(archive))
+			require.NoError(t, err)
+			_, err = reader.Next()
+			require.NoError(t, err)
+			_, err = reader.Next()
+			var headerErr *ErrHeader
+			assert.Equal(t, tc.AlwaysInvalid, errors.As(err, &headerErr))
+
+			reader, err = NewReaderWithOptions(strings.NewReader(archive), ReaderOptions{Strict: true})
+			require.NoError(t, err)
+			hdr, err := reader.Next()
+			require.NoError(t, err)
+			assert.Equal(t, "ok.txt", hdr.Name)
+			_, err = reader.Next()
+			require.ErrorAs(t, err, &headerErr)
+			assert.Equal(t, offset, headerErr.Offset)
+			assert.Equal(t, 1, headerErr.Index)
+			assert.EqualError(t, headerErr.Err, tc.Error)
+		})
+	}
+}
+
+func TestStrictTruncatedData(t *testing.T) {
+	// The size of an archive can't be determined in advance if it isn't seekable, so truncated data
+	// sections are only detected when the Reader tries to skip past them.
+	archive := GLOBAL_HEADER + fmt.Sprintf("%-16s%-12s%-6s%-6s%-8s%-10s`\n", "a.txt/", "0", "0", "0", "644", "100") + "short"
+	reader, err := NewReaderWithOptions(bytes.NewBufferString(archive), ReaderOptions{Strict: true})
+	require.NoError(t, err)
+	_, err = reader.Next()
+	require.NoError(t, err)
+	_, err = reader.Next()
+	var headerErr *ErrHeader
+	require.ErrorAs(t, err, &headerErr)
+	assert.Equal(t, int64(len(GLOBAL_HEADER)), headerErr.Offset)
+	assert.Equal(t, 0, headerErr.Index)
+	assert.EqualError(t, err, "ar: header (member 0 at offset 8): data section is truncated")
+}
+
+func TestStrictValidArchives(t *testing.T) {
+	for _, path := range []string{"hello.a", "long_filenames_bsd.a", "long_filenames_gnu.a", "symbols_gnu.a", "symbols_gnu64.a", "symbols_bsd.a"} {
+		t.Run(path, func(t *testing.T) {
+			f, err := os.Open("./test_data/" + path)
+			require.NoError(t, err)
+			defer f.Close()
+			reader, err := NewReaderWithOptions(f, ReaderOptions{Strict: true})
+			require.NoError(t, err)
+			for {
+				_, err := reader.Next()
+				if err == io.EOF {
+					break
+				}
+				require.NoError(t, err)
+				_, err = io.Copy(io.Discard, reader)
+				require.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestReaderLimits(t *testing.T) {
+	for _, tc := range []struct {
+		Description string
+		ArchivePath string
+		Options     ReaderOptions
+		// Members is the number of members that can be read before the limit is exceeded.
+		Members  int
+		Resource string
+	}{
+		{"String table size", "long_filenames_gnu.a", ReaderOptions{MaxStringTableSize: 10}, 0, "string table size"},
+		{"Symbol table size", "symbols_gnu.a", ReaderOptions{MaxSymbolTableSize: 10}, 0, "symbol table size"},
+		{"BSD symbol table size", "symbols_bsd.a", ReaderOptions{MaxSymbolTableSize: 10}, 0, "symbol table size"},
+		{"BSD file name length", "long_filenames_bsd.a", ReaderOptions{MaxNameLength: 10}, 10, "file name length"},
+		{"GNU file name length", "long_filenames_gnu.a", ReaderOptions{MaxNameLength: 10}, 10, "file name length"},
+		{"Member count", "long_filenames_gnu.a", ReaderOptions{MaxMembers: 5}, 5, "member count"},
+		{"Total member size", "long_filenames_gnu.a", ReaderOptions{MaxTotalSize: 3 * 47}, 3, "total member size"},
+	} {
+		t.Run(tc.Description, func(t *testing.T) {
+			f, err := os.Open("./test_data/" + tc.ArchivePath)
+			require.NoError(t, err)
+			defer f.Close()
+			reader, err := NewReaderWithOptions(f, tc.Options)
+			require.NoError(t, err)
+			for i := 0; i < tc.Members; i++ {
+				_, err := reader.Next()
+				require.NoError(t, err)
+			}
+			_, err = reader.Next()
+			var limitErr *ErrLimit
+			require.ErrorAs(t, err, &limitErr)
+			assert.Equal(t, tc.Resource, limitErr.Resource)
+			assert.Greater(t, limitErr.Value, limitErr.Limit)
+		})
+	}
+
+	// Malformed sizes and file names must be rejected, rather than slipping past the limits and causing
+	// the Reader to panic.
+	header := func(name string, size int) string {
+		return fmt.Sprintf("%-16s%-12s%-6s%-6s%-8s%-10d`\n", name, "0", "0", "0", "644", size)
+	}
+	var (
+		stringTableErr *ErrStringTable
+		fileNameErr    *ErrFileName
+		headerErr      *ErrHeader
+	)
+	for _, tc := range []struct {
+		Description string
+		Archive     string
+		Target      interface{}
+	}{
+		{"Negative string table size", header("//", -4), &stringTableErr},
+		{"Empty string table entry", header("//", 2) + "\n\n" + header("/0", 0), &fileNameErr},
+		{"Empty GNU-style string table entry", header("//", 2) + "/\n" + header("/0", 0), &fileNameErr},
+		{"Negative string table offset", header("//", 4) + "ab/\n" + header("/-1", 0), &fileNameErr},
+		{"Negative BSD file name length", header("#1/-3", 4) + "abcd", &fileNameErr},
+		{"BSD file name length exceeding member size", header("#1/20", 4) + "abcd", &fileNameErr},
+		{"Negative member size", header("a.txt/", -4), &headerErr},
+	} {
+		t.Run(tc.Description, func(t *testing.T) {
+			reader, err := NewReaderWithOptions(strings.NewReader(GLOBAL_HEADER+tc.Archive), ReaderOptions{
+				MaxStringTableSize: 1024,
+				MaxNameLength:      64,
+			})
+			require.NoError(t, err)
+			_, err = reader.Next()
+			assert.ErrorAs(t, err, tc.Target)
+		})
+	}
+}
+
+func TestReaderLimitsNotExceeded(t *testing.T) {
+	f, err := os.Open("./test_data/long_filenames_gnu.a")
+	require.NoError(t, err)
+	defer f.Close()
+	reader, err := NewReaderWithOptions(f, ReaderOptions{
+		MaxStringTableSize: 1024,
+		MaxNameLength:      20,
+		MaxMembers:         20,
+		MaxTotalSize:       2 * 1024,
+	})
+	require.NoError(t, err)
+	for i := 0; i < 20; i++ {
+		_, err := reader.Next()
+		require.NoError(t, err)
+	}
+	_, err = reader.Next()
+	assert.ErrorIs(t, err, io.EOF)
+}
+
+func TestReaderOffsets(t *testing.T) {
+	f, err := os.Open("./test_data/symbols_gnu.a")
+	require.NoError(t, err)
+	defer f.Close()
+	reader, err := NewReader(f)
+	require.NoError(t, err)
+	for _, offset := range []int64{100, 896} {
+		_, err := reader.Next()
+		require.NoError(t, err)
+		assert.Equal(t, offset, reader.HeaderOffset())
+		assert.Equal(t, offset+HEADER_BYTE_SIZE, reader.DataOffset())
+	}
+}
+
+func TestErrorLocation(t *testing.T) {
+	header := func(name string, size int) string {
+		return fmt.Sprintf("%-16s%-12s%-6s%-6s%-8s%-10d`\n", name, "0", "0", "0", "644", size)
+	}
+	stringTable := "a_long_file_name.txt/\n"
+	archive := GLOBAL_HEADER +
+		header("//", len(stringTable)) + stringTable +
+		header("a.txt/", 2) + "ok" +
+		header("/0", 2) + "ok" +
+		header("/99", 2) + "ok"
+	reader, err := NewReader(strings.NewReader(archive))
+	require.NoError(t, err)
+	for _, name := range []string{"a.txt", "a_long_file_name.txt"} {
+		hdr, err := reader.Next()
+		require.NoError(t, err)
+		assert.Equal(t, name, hdr.Name)
+	}
+	_, err = reader.Next()
+	var nameErr *ErrFileName
+	require.ErrorAs(t, err, &nameErr)
+	offset := int64(len(GLOBAL_HEADER) + 3*HEADER_BYTE_SIZE + len(stringTable) + 4)
+	assert.Equal(t, offset, nameErr.Offset)
+	assert.Equal(t, 3, nameErr.Index)
+	assert.Equal(t, offset, reader.HeaderOffset())
+	assert.EqualError(t, err, fmt.Sprintf("ar: archive member '/99' (member 3 at offset %d): invalid string table offset", offset))
+}
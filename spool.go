@@ -0,0 +1,79 @@
+package ar
+
+import (
+	"bytes"
+	"io"
+	"os"
+)
+
+// DefaultMaxMemory is the default number of bytes of archive members' data that a buffered Writer
+// holds in memory before moving it to a temporary file.
+const DefaultMaxMemory = 32 << 20
+
+// spool stores the data sections of the archive members supplied to a buffered Writer. Data is held
+// in memory until its size exceeds a limit, after which it is moved to a temporary file.
+type spool struct {
+	// max is the number of bytes of data that may be held in memory, or a negative number if there is
+	// no limit.
+	max int64
+
+	// buf holds the data, if it is being held in memory.
+	buf bytes.Buffer
+
+	// f is the temporary file holding the data, or nil if it is being held in memory.
+	f *os.File
+
+	// size is the number of bytes of data in the spool.
+	size int64
+}
+
+func (s *spool) Write(b []byte) (int, error) {
+	if s.f == nil && s.max >= 0 && s.size+int64(len(b)) > s.max {
+		f, err := os.CreateTemp("", "ar-spool-")
+		if err != nil {
+			return 0, err
+		}
+		s.f = f
+		if _, err := s.f.Write(s.buf.Bytes()); err != nil {
+			return 0, err
+		}
+		s.buf = bytes.Buffer{}
+	}
+	var n int
+	var err error
+	if s.f != nil {
+		n, err = s.f.Write(b)
+	} else {
+		n, err = s.buf.Write(b)
+	}
+	s.size += int64(n)
+	return n, err
+}
+
+// Len returns the number of bytes of data in the spool.
+func (s *spool) Len() int64 {
+	return s.size
+}
+
+// ReaderAt returns an io.ReaderAt that reads the data in the spool.
+func (s *spool) ReaderAt() io.ReaderAt {
+	if s.f != nil {
+		return s.f
+	}
+	return bytes.NewReader(s.buf.Bytes())
+}
+
+// Close discards the data in the spool, removing the temporary file if there is one.
+func (s *spool) Close() error {
+	s.buf = bytes.Buffer{}
+	if s.f == nil {
+		return nil
+	}
+	f := s.f
+	s.f = nil
+	err := f.Close()
+	if rerr := os.Remove(f.Name()); err == nil {
+		err = rerr
+	}
+	return err
+}
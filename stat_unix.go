@@ -0,0 +1,24 @@
+//go:build unix
+
+package ar
+
+import (
+	"io/fs"
+	"syscall"
+)
+
+func init() {
+	sysStat = statUnix
+}
+
+// statUnix populates the Uid and Gid fields of h from fi, if fi describes a file on the local file
+// system.
+func statUnix(fi fs.FileInfo, h *Header) error {
+	sys, ok := fi.Sys().(*syscall.Stat_t)
+	if !ok {
+		return nil
+	}
+	h.Uid = int(sys.Uid)
+	h.Gid = int(sys.Gid)
+	return nil
+}
@@ -0,0 +1,179 @@
+package ar
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"math"
+	"sort"
+)
+
+// Symbol is an entry in an archive's symbol table, which records which of the archive's members
+// defines a given symbol.
+type Symbol struct {
+	// Name is the name of the symbol.
+	Name string
+
+	// Offset is the byte offset of the header of the member that defines the symbol, relative to the
+	// start of the archive file.
+	Offset int64
+}
+
+// parseGNUSymbolTable decodes the data section of the special "/" and "/SYM64/" files in GNU-variant
+// archives. The data section begins with a big-endian integer containing the number of symbols in
+// the table, followed by an array of that many big-endian integers containing the offsets of the
+// member headers that define each symbol, followed by the symbols' names, each terminated by a NUL
+// byte. The integers are 64 bits wide in "/SYM64/" files if wide is true, and 32 bits wide otherwise.
+func parseGNUSymbolTable(data []byte, wide bool) ([]Symbol, error) {
+	size := 4
+	word := func(b []byte) uint64 { return uint64(binary.BigEndian.Uint32(b)) }
+	if wide {
+		size = 8
+		word = binary.BigEndian.Uint64
+	}
+	s := slicer(data)
+	if len(s) < size {
+		return nil, errors.New("truncated symbol count")
+	}
+	count := word(s.next(size))
+	if count > uint64(len(s)/size) {
+		return nil, errors.New("truncated offset array")
+	}
+	symbols := make([]Symbol, count)
+	for i := range symbols {
+		off := word(s.next(size))
+		if off > math.MaxInt64 {
+			return nil, errors.New("invalid member offset")
+		}
+		symbols[i].Offset = int64(off)
+	}
+	for i := range symbols {
+		end := bytes.IndexByte(s, 0)
+		if end == -1 {
+			return nil, errors.New("truncated symbol name list")
+		}
+		symbols[i].Name = string(s.next(end + 1)[:end])
+	}
+	return symbols, nil
+}
+
+// encodeGNUSymbolTable encodes the given symbols as the data section of a symbol table file in a
+// GNU-variant archive (see parseGNUSymbolTable for the format), returning the name of the file along
+// with the data section. The symbol table is written in the 64-bit "/SYM64/" format if any of the
+// symbols' member offsets cannot be represented in 32 bits, and the 32-bit "/" format otherwise.
+func encodeGNUSymbolTable(symbols []Symbol) (string, []byte) {
+	name, size := "/", 4
+	for _, symbol := range symbols {
+		if symbol.Offset > math.MaxUint32 {
+			name, size = "/SYM64/", 8
+			break
+		}
+	}
+	word := func(b []byte, x uint64) []byte {
+		if size == 8 {
+			return binary.BigEndian.AppendUint64(b, x)
+		}
+		return binary.BigEndian.AppendUint32(b, uint32(x))
+	}
+	data := word(nil, uint64(len(symbols)))
+	for _, symbol := range symbols {
+		data = word(data, uint64(symbol.Offset))
+	}
+	for _, symbol := range symbols {
+		data = append(data, symbol.Name...)
+		data = append(data, 0)
+	}
+	return name, data
+}
+
+// parseBSDSymbolTable decodes the data section of the special "__.SYMDEF" and "__.SYMDEF_64" files
+// (and their "SORTED" counterparts) in BSD-variant archives. The data section begins with a little-endian
+// integer containing the length in bytes of an array of ranlib structures, followed by that array;
+// each ranlib structure consists of a little-endian integer containing the offset of a symbol's
+// name within the string pool, and another containing the offset of the member header that defines
+// the symbol. The array is followed by a little-endian integer containing the length in bytes of the
+// string pool, followed by the string pool itself, which consists of NUL-terminated symbol names.
+// The integers are 64 bits wide in "__.SYMDEF_64" files, if wide is true, and 32 bits wide otherwise.
+func parseBSDSymbolTable(data []byte, wide bool) ([]Symbol, error) {
+	size := 4
+	word := func(b []byte) uint64 { return uint64(binary.LittleEndian.Uint32(b)) }
+	if wide {
+		size = 8
+		word = binary.LittleEndian.Uint64
+	}
+	s := slicer(data)
+	if len(s) < size {
+		return nil, errors.New("truncated ranlib array length")
+	}
+	ranlibLen := word(s.next(size))
+	if ranlibLen%uint64(2*size) != 0 {
+		return nil, errors.New("invalid ranlib array length")
+	}
+	if ranlibLen > uint64(len(s)) {
+		return nil, errors.New("truncated ranlib array")
+	}
+	ranlibs := slicer(s.next(int(ranlibLen)))
+	if len(s) < size {
+		return nil, errors.New("truncated string pool length")
+	}
+	poolLen := word(s.next(size))
+	if poolLen > uint64(len(s)) {
+		return nil, errors.New("truncated string pool")
+	}
+	pool := s.next(int(poolLen))
+	symbols := make([]Symbol, len(ranlibs)/(2*size))
+	for i := range symbols {
+		strx := word(ranlibs.next(size))
+		off := word(ranlibs.next(size))
+		if strx >= uint64(len(pool)) {
+			return nil, errors.New("invalid string pool offset")
+		}
+		end := bytes.IndexByte(pool[strx:], 0)
+		if end == -1 {
+			return nil, errors.New("unterminated symbol name")
+		}
+		if off > math.MaxInt64 {
+			return nil, errors.New("invalid member offset")
+		}
+		symbols[i] = Symbol{
+			Name:   string(pool[strx : strx+uint64(end)]),
+			Offset: int64(off),
+		}
+	}
+	return symbols, nil
+}
+
+// encodeBSDSymbolTable encodes the given symbols as the data section of a symbol table file in a
+// BSD-variant archive (see parseBSDSymbolTable for the format), returning the name of the file along
+// with the data section. The symbols are sorted by name, as the linker expects of a "SORTED" symbol
+// table. The symbol table is written in the 64-bit "__.SYMDEF_64 SORTED" format if any of the
+// symbols' member offsets cannot be represented in 32 bits, and the 32-bit "__.SYMDEF SORTED" format
+// otherwise.
+func encodeBSDSymbolTable(symbols []Symbol) (string, []byte) {
+	name, size := "__.SYMDEF SORTED", 4
+	for _, symbol := range symbols {
+		if symbol.Offset > math.MaxUint32 {
+			name, size = "__.SYMDEF_64 SORTED", 8
+			break
+		}
+	}
+	word := func(b []byte, x uint64) []byte {
+		if size == 8 {
+			return binary.LittleEndian.AppendUint64(b, x)
+		}
+		return binary.LittleEndian.AppendUint32(b, uint32(x))
+	}
+	sorted := make([]Symbol, len(symbols))
+	copy(sorted, symbols)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Name < sorted[j].Name })
+	var pool []byte
+	data := word(nil, uint64(2*size*len(sorted)))
+	for _, symbol := range sorted {
+		data = word(data, uint64(len(pool)))
+		data = word(data, uint64(symbol.Offset))
+		pool = append(pool, symbol.Name...)
+		pool = append(pool, 0)
+	}
+	data = word(data, uint64(len(pool)))
+	return name, append(data, pool...)
+}
@@ -0,0 +1,52 @@
+package ar
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// Resolver opens the external file containing the data section of a member of a thin archive. name
+// is the member's name as recorded in the archive, which is the path to the external file relative
+// to the directory containing the archive file (unless it is an absolute path).
+type Resolver func(name string) (io.ReadCloser, error)
+
+// DirResolver returns a Resolver that opens the external files containing the data sections of the
+// members of a thin archive located in the directory dir.
+func DirResolver(dir string) Resolver {
+	return func(name string) (io.ReadCloser, error) {
+		path := filepath.FromSlash(name)
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(dir, path)
+		}
+		return os.Open(path)
+	}
+}
+
+// readExternal reads data from the current member of a thin archive, opening the external file that
+// contains it if necessary.
+func (rd *Reader) readExternal(b []byte) (int, error) {
+	if rd.externalData == nil {
+		if rd.resolver == nil {
+			return 0, ErrNoResolver
+		}
+		f, err := rd.resolver(rd.external.Name)
+		if err != nil {
+			return 0, &ErrFileName{Name: rd.external.Name, Offset: rd.headerOffset, Index: rd.index, Err: err}
+		}
+		rd.externalFile = f
+		rd.externalData = io.LimitReader(f, rd.external.Size)
+	}
+	return rd.externalData.Read(b)
+}
+
+// closeExternal closes the external file containing the data section of the current member of a thin
+// archive, if it has been opened.
+func (rd *Reader) closeExternal() error {
+	f := rd.externalFile
+	rd.external, rd.externalData, rd.externalFile = nil, nil, nil
+	if f == nil {
+		return nil
+	}
+	return f.Close()
+}
@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -60,6 +61,88 @@ type Writer struct {
 	// This field's value is only meaningful when variant is GNU - BSD-style archives do not
 	// contain a string table.
 	stringTable map[string]int
+
+	// opts is the optional behaviour requested when the Writer was created.
+	opts WriterOptions
+
+	// buffered is true if archive members are held until Close is called, rather than being written to
+	// the underlying io.Writer as they are supplied.
+	buffered bool
+
+	// members is the list of archive members supplied so far, if buffered is true.
+	members []bufferedMember
+
+	// spool holds the data sections of the archive members supplied so far, if buffered is true.
+	spool *spool
+
+	// longNames is the list of file names passed to WriteStringTable, if buffered is true.
+	longNames []string
+
+	// pad is true if the current member's data section has an odd length, and therefore needs to be
+	// followed by a padding byte once it has been completely written.
+	pad bool
+
+	// discard is true if data written via Write since the most recent call to WriteHeader should be
+	// discarded rather than written to the archive, which is the case for members of thin archives.
+	discard bool
+
+	// coff is true if the archive is written in the format produced by Microsoft's tools, in which a
+	// generated symbol table is followed by a second linker member and the file names in the string
+	// table are terminated with NUL bytes. It is set when rewriting an archive in that format.
+	coff bool
+}
+
+// bufferedMember is an archive member that is held by a buffered Writer until Close is called.
+type bufferedMember struct {
+	// hdr is the archive member's header.
+	hdr Header
+
+	// off is the byte offset of the archive member's data section within the Writer's spool.
+	off int64
+}
+
+// WriterOptions specifies optional behaviour for a Writer created with NewWriterWithOptions.
+type WriterOptions struct {
+	// SymbolIndex causes the Writer to generate a symbol table (the index usually generated by ranlib)
+	// listing the global symbols defined by the ELF and Mach-O object files in the archive, which is
+	// written as the first member of the archive ("/" in GNU-variant archives, or "__.SYMDEF SORTED"
+	// in BSD-variant archives). Like GNU ar, the Writer omits the symbol table from GNU-variant
+	// archives if none of the members define any global symbols.
+	//
+	// Because the symbol table depends on the contents of all of the other members, setting
+	// SymbolIndex implies Buffer.
+	SymbolIndex bool
+
+	// Buffer causes the Writer to hold the archive's members until Close is called, at which point the
+	// entire archive is written to the underlying io.Writer. This allows the Writer to generate the
+	// string table for GNU-variant archives itself, so calling WriteStringTable is unnecessary.
+	//
+	// Members' data sections are held in memory until their total size exceeds MaxMemory bytes, after
+	// which they are moved to a temporary file (which is removed when Close is called).
+	Buffer bool
+
+	// MaxMemory is the number of bytes of members' data sections that the Writer holds in memory
+	// before moving them to a temporary file, if Buffer is set. If it is zero, DefaultMaxMemory is
+	// used; if it is negative, members' data sections are always held in memory.
+	MaxMemory int64
+
+	// Thin causes the Writer to write a thin archive, in which members consist only of a header; their
+	// data sections are stored in external files rather than in the archive itself. Members' file
+	// names are stored in the string table, and should be the paths to the external files relative to
+	// the directory containing the archive. Thin archives must use the GNU variant.
+	//
+	// Data written via Write is not stored in the archive. If SymbolIndex is also set, the data is
+	// used to generate the symbol table, so callers should supply the external files' contents;
+	// otherwise, it is discarded, and callers need not supply it at all.
+	Thin bool
+
+	// Deterministic causes the Writer to write archives that depend only on the names, order and
+	// contents of their members (like GNU ar's "D" modifier), so that identical archives are produced
+	// regardless of the environment they are created in. Members' modification times are set to Epoch,
+	// their owner and group IDs are set to 0, and their modes are set to 0644, regardless of the values
+	// in the headers passed to WriteHeader. The entries in GNU-variant archives' string tables are
+	// sorted by file name.
+	Deterministic bool
 }
 
 // NewWriter creates a new Writer that writes an ar archive of the given variant to an underlying
@@ -72,6 +155,28 @@ func NewWriter(w io.Writer, variant Variant) *Writer {
 	}
 }
 
+// NewWriterWithOptions creates a new Writer that writes an ar archive of the given variant to an
+// underlying io.Writer, with the optional behaviour specified by opts.
+func NewWriterWithOptions(w io.Writer, variant Variant, opts WriterOptions) *Writer {
+	aw := newWriter(w, variant, opts)
+	if opts.Buffer || opts.SymbolIndex {
+		aw.buffered = true
+		aw.spool = &spool{max: opts.MaxMemory}
+		if aw.spool.max == 0 {
+			aw.spool.max = DefaultMaxMemory
+		}
+	}
+	return aw
+}
+
+// newWriter creates a new Writer with the optional behaviour specified by opts that always writes
+// archive members to w as they are supplied.
+func newWriter(w io.Writer, variant Variant, opts WriterOptions) *Writer {
+	aw := NewWriter(w, variant)
+	aw.opts = opts
+	return aw
+}
+
 func (aw *Writer) numeric(b []byte, x int64) {
 	s := strconv.FormatInt(x, 10)
 	for len(s) < len(b) {
@@ -110,6 +215,17 @@ func (aw *Writer) Close() error {
 	if aw.closed {
 		return errors.New("ar: writer closed twice")
 	}
+	if aw.buffered {
+		if aw.nb > 0 && !aw.discardsData() {
+			return fmt.Errorf("ar: missed writing %d bytes", aw.nb)
+		}
+		aw.closed = true
+		err := aw.flush()
+		if cerr := aw.spool.Close(); err == nil {
+			err = cerr
+		}
+		return err
+	}
 	aw.writeHeader()
 	aw.closed = true
 	return nil
@@ -123,13 +239,35 @@ func (aw *Writer) Write(b []byte) (n int, err error) {
 		b = b[0:aw.nb]
 		err = ErrWriteTooLong
 	}
+	if aw.discard {
+		n = len(b)
+		aw.nb -= int64(n)
+		return
+	}
+	if aw.buffered {
+		if aw.closed {
+			return 0, errors.New("ar: write to closed writer")
+		}
+		if aw.discardsData() {
+			n = len(b)
+			aw.nb -= int64(n)
+			return n, err
+		}
+		n, werr := aw.spool.Write(b)
+		aw.nb -= int64(n)
+		if werr != nil {
+			return n, fmt.Errorf("ar: buffer member data: %w", werr)
+		}
+		return n, err
+	}
 	n, werr := aw.write(b)
 	aw.nb -= int64(n)
 	if werr != nil {
 		return n, werr
 	}
 
-	if len(b)%2 == 1 { // data size must be aligned to an even byte
+	if aw.nb == 0 && aw.pad { // data size must be aligned to an even byte
+		aw.pad = false
 		if _, err := aw.write([]byte{'\n'}); err != nil {
 			// Return n although we actually wrote n+1 bytes.
 			// This is to make io.Copy() to work correctly.
@@ -147,13 +285,54 @@ func (aw *Writer) writeHeader() error {
 		return nil
 	}
 	aw.wroteHeader = true
-	_, err := aw.write([]byte(GLOBAL_HEADER))
+	header := GLOBAL_HEADER
+	if aw.opts.Thin {
+		header = THIN_GLOBAL_HEADER
+	}
+	_, err := aw.write([]byte(header))
 	if err != nil {
 		return fmt.Errorf("ar: write archive header: %w", err)
 	}
 	return nil
 }
 
+// WriteSymbolTable writes a symbol table.
+//
+// The symbol table lists the symbols defined by the archive's members, along with the byte offset
+// (relative to the start of the archive file) of the header of the member that defines each symbol.
+// It must be the first member of the archive, which means that this function must be called before
+// any call to WriteStringTable or WriteHeader; the offsets must therefore account for the size of
+// the symbol table itself. The symbol table is written in a 64-bit format ("/SYM64/" or
+// "__.SYMDEF_64 SORTED") if any of the offsets exceeds 32 bits, or a 32-bit format ("/" or
+// "__.SYMDEF SORTED") otherwise.
+//
+// This function must not be called if this Writer was created with the SymbolIndex option, which
+// generates the symbol table automatically.
+func (aw *Writer) WriteSymbolTable(symbols []Symbol) error {
+	if aw.buffered && aw.opts.SymbolIndex {
+		return errors.New("ar: wrote symbol table for archive with generated symbol index")
+	}
+	if aw.wroteHeader {
+		return errors.New("ar: symbol table must be the first archive member")
+	}
+	var name string
+	var data []byte
+	switch aw.variant {
+	case GNU:
+		name, data = encodeGNUSymbolTable(symbols)
+	case BSD:
+		name, data = encodeBSDSymbolTable(symbols)
+	default:
+		// This should be unreachable.
+		return errors.New("ar: unsupported variant")
+	}
+	if err := aw.WriteHeader(&Header{Name: name, Size: int64(len(data))}); err != nil {
+		return err
+	}
+	_, err := io.Copy(aw, bytes.NewReader(data))
+	return err
+}
+
 // WriteStringTable writes a string table for GNU-format archives.
 //
 // The string table is a list of file names of archive members that are more than 15 bytes long
@@ -162,6 +341,10 @@ func (aw *Writer) writeHeader() error {
 // must be called before the first call to WriteHeader if the archive is to contain members with a file
 // name length of more than 15 bytes.
 //
+// Writers created with the Buffer option generate the string table automatically, so calling this
+// function is unnecessary; if it is called, the given file names appear at the start of the string
+// table, followed by any other file names that need to be stored there.
+//
 // The BSD variant of the ar file format has no concept of string tables, and this function has no
 // effect if this Writer is writing a BSD-format archive.
 func (aw *Writer) WriteStringTable(filenames []string) error {
@@ -172,12 +355,22 @@ func (aw *Writer) WriteStringTable(filenames []string) error {
 		return errors.New("ar: wrote string table twice")
 	}
 	aw.wroteStringTable = true
+	if aw.buffered {
+		aw.longNames = filenames
+		return nil
+	}
+	if aw.opts.Deterministic {
+		filenames = sortedUnique(filenames)
+	}
 	var data []byte
 	for _, filename := range filenames {
 		aw.stringTable[filename] = len(data)
 		data = append(data, []byte(filename)...)
-		data = append(data, '/')
-		data = append(data, '\n')
+		if aw.coff {
+			data = append(data, 0)
+		} else {
+			data = append(data, '/', '\n')
+		}
 	}
 	if len(data) == 0 {
 		return nil
@@ -193,20 +386,37 @@ func (aw *Writer) WriteStringTable(filenames []string) error {
 // Writes the header to the underlying writer and prepares
 // to receive the file payload
 func (aw *Writer) WriteHeader(hdr *Header) error {
+	if aw.buffered {
+		return aw.bufferHeader(hdr)
+	}
 	aw.nb = int64(hdr.Size)
+	aw.pad = false
+	aw.discard = false
 	header := make([]byte, HEADER_BYTE_SIZE)
 	s := slicer(header)
 
 	if len(hdr.Name) == 0 {
 		return errors.New("ar: empty file name")
 	}
+	if aw.opts.Thin && aw.variant != GNU {
+		return errors.New("ar: thin archives must use the GNU variant")
+	}
+	special := aw.variant == GNU && specialGNUName(hdr.Name)
+	if aw.opts.Deterministic && !special {
+		h := *hdr
+		h.ModTime = Epoch
+		h.Uid, h.Gid = 0, 0
+		h.Mode = 0644
+		hdr = &h
+	}
 
 	switch aw.variant {
 	case GNU:
 		// "/" is always appended to GNU-variant file names, which means that any file names over 15 bytes
 		// long must be stored in the string table, even though there's 16 bytes of space in the header
-		// for the file name.
-		if len(hdr.Name) > 15 {
+		// for the file name. The file names of thin archives' members are paths, which may contain "/"
+		// and must therefore also be stored in the string table.
+		if aw.longName(hdr.Name) {
 			if !aw.wroteStringTable {
 				return errors.New("ar: missing string table")
 			}
@@ -230,7 +440,7 @@ func (aw *Writer) WriteHeader(hdr *Header) error {
 		// the spaces in the field padding, also do this for file names containing spaces (even when the
 		// spaces occur before the end of the file name, in case the reader reads the file name header
 		// byte by byte and stops when it encounters the first space).
-		if len(hdr.Name) > 16 || strings.ContainsRune(hdr.Name, ' ') {
+		if bsdLongName(hdr.Name) {
 			// The ar file format requires data sections to be an even number of bytes long. Since the real
 			// file name is being prepended to the data section, pad it with one null byte if it has an odd
 			// length (the padding byte will be ignored when read). Write will take care of the padding for
@@ -265,17 +475,56 @@ func (aw *Writer) WriteHeader(hdr *Header) error {
 	}
 	aw.numeric(s.next(10), hdr.Size)
 	aw.string(s.next(2), "`\n")
+	aw.pad = hdr.Size%2 == 1
 
 	_, err := aw.write(header)
 	if err != nil {
 		return fmt.Errorf("ar: write member header: %w", err)
 	}
 
-	if aw.variant == BSD && len(hdr.Name) > 16 {
+	if aw.variant == BSD && bsdLongName(hdr.Name) {
 		if _, err = aw.Write([]byte(hdr.Name)); err != nil {
 			return fmt.Errorf("ar: write BSD-variant file name: %w", err)
 		}
 	}
 
+	// Thin archives only contain the data sections of special members.
+	aw.discard = aw.opts.Thin && !special
+
 	return nil
 }
+
+// longName returns whether the given file name must be stored in the string table of a GNU-variant
+// archive, rather than in the member's header.
+func (aw *Writer) longName(name string) bool {
+	if specialGNUName(name) {
+		return false
+	}
+	return len(name) > 15 || (aw.opts.Thin && strings.Contains(name, "/"))
+}
+
+// specialGNUName returns whether the given file name is that of one of the special members of a
+// GNU-variant archive, which contain the archive's symbol and string tables.
+func specialGNUName(name string) bool {
+	return name == "/" || name == "//" || name == "/SYM64/"
+}
+
+// bsdLongName returns whether the given file name must be prepended to the data section of a member
+// in a BSD-variant archive, rather than being stored in the member's header.
+func bsdLongName(name string) bool {
+	return len(name) > 16 || strings.ContainsRune(name, ' ')
+}
+
+// sortedUnique returns a sorted copy of the given strings with duplicates removed.
+func sortedUnique(strs []string) []string {
+	sorted := make([]string, len(strs))
+	copy(sorted, strs)
+	sort.Strings(sorted)
+	unique := sorted[:0]
+	for i, s := range sorted {
+		if i == 0 || s != sorted[i-1] {
+			unique = append(unique, s)
+		}
+	}
+	return unique
+}
@@ -3,12 +3,14 @@ package ar
 import (
 	"bytes"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"strconv"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -36,6 +38,30 @@ func TestWriteTooLong(t *testing.T) {
 	assert.ErrorIs(t, err, ErrWriteTooLong)
 }
 
+func TestWriteBSDNameWithSpace(t *testing.T) {
+	// File names containing spaces are stored after BSD-variant members' headers, even if they're short
+	// enough to fit in the headers, since readers treat spaces in the header as padding.
+	var buf bytes.Buffer
+	writer := NewWriter(&buf, BSD)
+	require.NoError(t, writer.WriteHeader(&Header{Name: "a b.txt", Mode: 0644, Size: 6}))
+	_, err := writer.Write([]byte("hello\n"))
+	require.NoError(t, err)
+	require.NoError(t, writer.Close())
+	assert.Contains(t, buf.String(), "#1/8 ")
+
+	reader, err := NewReader(&buf)
+	require.NoError(t, err)
+	hdr, err := reader.Next()
+	require.NoError(t, err)
+	assert.Equal(t, "a b.txt", hdr.Name)
+	assert.Equal(t, int64(6), hdr.Size)
+	data, err := io.ReadAll(reader)
+	require.NoError(t, err)
+	assert.Equal(t, "hello\n", string(data))
+	_, err = reader.Next()
+	assert.ErrorIs(t, err, io.EOF)
+}
+
 // TestWriteValidArchive ensures that the archive files written by Writer are capable of being read
 // by a range of third-party ar tools. Subsets of the test cases run in different CI environments
 // depending on the availability of the third-party ar tools on each runner type.
@@ -138,3 +164,355 @@ func TestWriteValidArchive(t *testing.T) {
 		})
 	}
 }
+
+func TestWriteSymbolTable(t *testing.T) {
+	for _, tc := range []struct {
+		Description string
+		Symbols     []Symbol
+		Name        string
+	}{
+		{
+			Description: "32-bit offsets",
+			Symbols:     []Symbol{{Name: "foo", Offset: 84}, {Name: "bar", Offset: 84}},
+			Name:        "/",
+		},
+		{
+			Description: "64-bit offsets",
+			Symbols:     []Symbol{{Name: "foo", Offset: 84}, {Name: "bar", Offset: 1 << 33}},
+			Name:        "/SYM64/",
+		},
+	} {
+		t.Run(tc.Description, func(t *testing.T) {
+			var buf bytes.Buffer
+			writer := NewWriter(&buf, GNU)
+			require.NoError(t, writer.WriteSymbolTable(tc.Symbols))
+			require.NoError(t, writer.WriteHeader(&Header{Name: "foo.o", Size: 3}))
+			_, err := writer.Write([]byte("foo"))
+			require.NoError(t, err)
+			require.NoError(t, writer.Close())
+			assert.Equal(t, tc.Name, strings.TrimRight(string(buf.Bytes()[8:24]), " "))
+
+			reader, err := NewReader(&buf)
+			require.NoError(t, err)
+			hdr, err := reader.Next()
+			require.NoError(t, err)
+			assert.Equal(t, "foo.o", hdr.Name)
+			assert.Equal(t, tc.Symbols, reader.Symbols())
+		})
+	}
+}
+
+func TestWriteSymbolTableOrdering(t *testing.T) {
+	var buf bytes.Buffer
+	writer := NewWriter(&buf, GNU)
+	require.NoError(t, writer.WriteStringTable([]string{"a_long_file_name.o"}))
+	assert.Error(t, writer.WriteSymbolTable([]Symbol{{Name: "foo", Offset: 8}}))
+}
+
+// readMembers returns the headers and data sections of the members of the archive at the given path.
+func readMembers(t *testing.T, path string) ([]*Header, [][]byte) {
+	f, err := os.Open(path)
+	require.NoError(t, err)
+	defer f.Close()
+	reader, err := NewReader(f)
+	require.NoError(t, err)
+	var hdrs []*Header
+	var data [][]byte
+	for {
+		hdr, err := reader.Next()
+		if err == io.EOF {
+			return hdrs, data
+		}
+		require.NoError(t, err)
+		b, err := io.ReadAll(reader)
+		require.NoError(t, err)
+		hdrs = append(hdrs, hdr)
+		data = append(data, b)
+	}
+}
+
+func TestWriteSymbolIndex(t *testing.T) {
+	hdrs, data := readMembers(t, "./test_data/symbols_gnu.a")
+	for _, tc := range []struct {
+		Description string
+		Variant     Variant
+		Prefix      string
+	}{
+		{"GNU format", GNU, ""},
+		{"BSD format", BSD, ""},
+		{"GNU format with long file names", GNU, "a_very_long_prefix_"},
+		{"BSD format with long file names", BSD, "a_very_long_prefix_"},
+	} {
+		t.Run(tc.Description, func(t *testing.T) {
+			var buf bytes.Buffer
+			writer := NewWriterWithOptions(&buf, tc.Variant, WriterOptions{SymbolIndex: true})
+			require.NoError(t, writer.WriteHeader(&Header{Name: tc.Prefix + "README", Mode: 0644, Size: 5}))
+			_, err := writer.Write([]byte("hello"))
+			require.NoError(t, err)
+			for i, hdr := range hdrs {
+				h := *hdr
+				h.Name = tc.Prefix + h.Name
+				require.NoError(t, writer.WriteHeader(&h))
+				_, err := writer.Write(data[i])
+				require.NoError(t, err)
+			}
+			require.NoError(t, writer.Close())
+
+			reader, err := NewReader(&buf)
+			require.NoError(t, err)
+			assert.Equal(t, tc.Variant, reader.Variant())
+			for {
+				_, err := reader.Next()
+				if err == io.EOF {
+					break
+				}
+				require.NoError(t, err)
+			}
+			assert.Len(t, reader.Symbols(), 3)
+			table := reader.SymbolTable()
+			require.Len(t, table, 3)
+			assert.Equal(t, tc.Prefix+"add.o", table["add"].Name)
+			assert.Equal(t, tc.Prefix+"add.o", table["counter"].Name)
+			assert.Equal(t, tc.Prefix+"sub.o", table["sub"].Name)
+		})
+	}
+}
+
+func TestWriteSymbolIndexMatchesGNUAr(t *testing.T) {
+	expected, err := os.ReadFile("./test_data/symbols_gnu.a")
+	require.NoError(t, err)
+	hdrs, data := readMembers(t, "./test_data/symbols_gnu.a")
+	var buf bytes.Buffer
+	writer := NewWriterWithOptions(&buf, GNU, WriterOptions{SymbolIndex: true})
+	for i, hdr := range hdrs {
+		require.NoError(t, writer.WriteHeader(hdr))
+		_, err := writer.Write(data[i])
+		require.NoError(t, err)
+	}
+	require.NoError(t, writer.Close())
+	assert.Equal(t, expected, buf.Bytes())
+}
+
+func TestWriteSymbolIndexIncompleteMember(t *testing.T) {
+	var buf bytes.Buffer
+	writer := NewWriterWithOptions(&buf, GNU, WriterOptions{SymbolIndex: true})
+	require.NoError(t, writer.WriteHeader(&Header{Name: "short.o", Size: 10}))
+	_, err := writer.Write([]byte("short"))
+	require.NoError(t, err)
+	assert.Error(t, writer.Close())
+	assert.Zero(t, buf.Len())
+}
+
+func TestWriteThinArchive(t *testing.T) {
+	names := []string{"objs/add.o", "objs/sub/a_long_object_name.o", "objs/h.txt"}
+	for _, tc := range []struct {
+		Description string
+		Options     WriterOptions
+	}{
+		{"Unbuffered", WriterOptions{Thin: true}},
+		{"Buffered", WriterOptions{Thin: true, Buffer: true}},
+	} {
+		t.Run(tc.Description, func(t *testing.T) {
+			var buf bytes.Buffer
+			writer := NewWriterWithOptions(&buf, GNU, tc.Options)
+			require.NoError(t, writer.WriteStringTable(names))
+			// The members' data sections needn't be written, since they're stored in external files.
+			for _, name := range names {
+				fi, err := os.Stat("./test_data/" + name)
+				require.NoError(t, err)
+				require.NoError(t, writer.WriteHeader(&Header{Name: name, Mode: 0644, Size: fi.Size()}))
+			}
+			require.NoError(t, writer.Close())
+			assert.True(t, bytes.HasPrefix(buf.Bytes(), []byte("!<thin>\n")))
+
+			reader, err := NewReaderWithOptions(&buf, ReaderOptions{Resolver: DirResolver("./test_data")})
+			require.NoError(t, err)
+			assert.True(t, reader.Thin())
+			for _, name := range names {
+				hdr, err := reader.Next()
+				require.NoError(t, err)
+				assert.Equal(t, name, hdr.Name)
+				expected, err := os.ReadFile("./test_data/" + name)
+				require.NoError(t, err)
+				actual, err := io.ReadAll(reader)
+				require.NoError(t, err)
+				assert.Equal(t, expected, actual)
+			}
+			_, err = reader.Next()
+			assert.ErrorIs(t, err, io.EOF)
+		})
+	}
+}
+
+func TestWriteThinArchiveSymbolIndex(t *testing.T) {
+	f, err := os.Open("./test_data/thin.a")
+	require.NoError(t, err)
+	defer f.Close()
+	reader, err := NewReaderWithOptions(f, ReaderOptions{Resolver: DirResolver("./test_data")})
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	writer := NewWriterWithOptions(&buf, GNU, WriterOptions{SymbolIndex: true, Thin: true})
+	var names []string
+	for {
+		hdr, err := reader.Next()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		names = append(names, hdr.Name)
+		require.NoError(t, writer.WriteHeader(hdr))
+		_, err = io.Copy(writer, reader)
+		require.NoError(t, err)
+	}
+	require.NoError(t, writer.Close())
+
+	// The symbol table should be identical to the one generated by GNU ar.
+	written, err := NewReader(&buf)
+	require.NoError(t, err)
+	assert.True(t, written.Thin())
+	for _, name := range names {
+		hdr, err := written.Next()
+		require.NoError(t, err)
+		assert.Equal(t, name, hdr.Name)
+	}
+	_, err = written.Next()
+	assert.ErrorIs(t, err, io.EOF)
+	assert.Equal(t, reader.Symbols(), written.Symbols())
+}
+
+func TestWriteThinArchiveBSD(t *testing.T) {
+	var buf bytes.Buffer
+	writer := NewWriterWithOptions(&buf, BSD, WriterOptions{Thin: true})
+	assert.Error(t, writer.WriteHeader(&Header{Name: "foo.o", Size: 1}))
+}
+
+func TestWriteBuffered(t *testing.T) {
+	for _, tc := range []struct {
+		Description string
+		Variant     Variant
+		MaxMemory   int64
+	}{
+		{"GNU format in memory", GNU, 0},
+		{"GNU format in temporary file", GNU, 64},
+		{"BSD format in memory", BSD, -1},
+		{"BSD format in temporary file", BSD, 64},
+	} {
+		t.Run(tc.Description, func(t *testing.T) {
+			tmp := t.TempDir()
+			t.Setenv("TMPDIR", tmp)
+			var buf bytes.Buffer
+			writer := NewWriterWithOptions(&buf, tc.Variant, WriterOptions{Buffer: true, MaxMemory: tc.MaxMemory})
+			for i := 1; i <= 20; i++ {
+				data := fmt.Sprintf("The name of this file contains %d character(s).\n", i)
+				err := writer.WriteHeader(&Header{
+					Name: fmt.Sprintf("%d%s", i, strings.Repeat("x", i-len(strconv.Itoa(i)))),
+					Mode: 0644,
+					Size: int64(len(data)),
+				})
+				require.NoError(t, err)
+				// Write the data in odd-sized chunks, as a streaming producer might.
+				for _, chunk := range []string{data[:5], data[5:]} {
+					_, err := writer.Write([]byte(chunk))
+					require.NoError(t, err)
+				}
+			}
+			// Nothing should be written until Close is called.
+			assert.Zero(t, buf.Len())
+			require.NoError(t, writer.Close())
+			entries, err := os.ReadDir(tmp)
+			require.NoError(t, err)
+			assert.Empty(t, entries, "temporary file should be removed")
+
+			reader, err := NewReader(&buf)
+			require.NoError(t, err)
+			assert.Equal(t, tc.Variant, reader.Variant())
+			for i := 1; i <= 20; i++ {
+				hdr, err := reader.Next()
+				require.NoError(t, err)
+				assert.Equal(t, fmt.Sprintf("%d%s", i, strings.Repeat("x", i-len(strconv.Itoa(i)))), hdr.Name)
+				b, err := io.ReadAll(reader)
+				require.NoError(t, err)
+				assert.Equal(t, fmt.Sprintf("The name of this file contains %d character(s).\n", i), string(b))
+			}
+			_, err = reader.Next()
+			assert.ErrorIs(t, err, io.EOF)
+		})
+	}
+}
+
+func TestWriteOddChunks(t *testing.T) {
+	var buf bytes.Buffer
+	writer := NewWriter(&buf, GNU)
+	require.NoError(t, writer.WriteHeader(&Header{Name: "abc.txt", Size: 3}))
+	for _, chunk := range []string{"a", "b", "c"} {
+		_, err := writer.Write([]byte(chunk))
+		require.NoError(t, err)
+	}
+	require.NoError(t, writer.WriteHeader(&Header{Name: "de.txt", Size: 2}))
+	_, err := writer.Write([]byte("de"))
+	require.NoError(t, err)
+	require.NoError(t, writer.Close())
+
+	reader, err := NewReader(&buf)
+	require.NoError(t, err)
+	for _, expected := range []string{"abc", "de"} {
+		_, err := reader.Next()
+		require.NoError(t, err)
+		b, err := io.ReadAll(reader)
+		require.NoError(t, err)
+		assert.Equal(t, expected, string(b))
+	}
+}
+
+func TestWriteDeterministic(t *testing.T) {
+	write := func(variant Variant, opts WriterOptions, longNames []string, hdrs []*Header) []byte {
+		var buf bytes.Buffer
+		writer := NewWriterWithOptions(&buf, variant, opts)
+		if longNames != nil {
+			require.NoError(t, writer.WriteStringTable(longNames))
+		}
+		for _, hdr := range hdrs {
+			require.NoError(t, writer.WriteHeader(hdr))
+			_, err := writer.Write(bytes.Repeat([]byte("x"), int(hdr.Size)))
+			require.NoError(t, err)
+		}
+		require.NoError(t, writer.Close())
+		return buf.Bytes()
+	}
+	for _, tc := range []struct {
+		Description string
+		Variant     Variant
+		Opts        WriterOptions
+		LongNames   [2][]string
+	}{
+		{"GNU format", GNU, WriterOptions{Deterministic: true}, [2][]string{
+			{"a_long_file_name_1.o", "a_long_file_name_2.o"},
+			{"a_long_file_name_2.o", "a_long_file_name_1.o", "a_long_file_name_2.o"},
+		}},
+		{"GNU format with buffering", GNU, WriterOptions{Deterministic: true, Buffer: true}, [2][]string{
+			{"a_long_file_name_2.o"},
+			nil,
+		}},
+		{"BSD format", BSD, WriterOptions{Deterministic: true}, [2][]string{nil, nil}},
+	} {
+		t.Run(tc.Description, func(t *testing.T) {
+			var archives [2][]byte
+			for i, uid := range []int{501, 1000} {
+				archives[i] = write(tc.Variant, tc.Opts, tc.LongNames[i], []*Header{
+					{Name: "a_long_file_name_1.o", ModTime: time.Now(), Uid: uid, Gid: uid, Mode: 0100600 + int64(i), Size: 3},
+					{Name: "a_long_file_name_2.o", ModTime: time.Unix(int64(i), 0), Uid: uid, Gid: uid, Mode: 0100755, Size: 4},
+				})
+			}
+			assert.Equal(t, archives[0], archives[1])
+
+			reader, err := NewReader(bytes.NewReader(archives[0]))
+			require.NoError(t, err)
+			for _, name := range []string{"a_long_file_name_1.o", "a_long_file_name_2.o"} {
+				hdr, err := reader.Next()
+				require.NoError(t, err)
+				assert.Equal(t, &Header{Name: name, ModTime: Epoch, Mode: 0644, Size: hdr.Size}, hdr)
+			}
+		})
+	}
+}